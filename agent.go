@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/commoddity/relay-util/v2/distributed"
+	"github.com/commoddity/relay-util/v2/log"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/tracing"
+	"github.com/spf13/pflag"
+)
+
+// agentCommand registers with a coordinator, sends its share of the run and streams the results back.
+func agentCommand(args []string) {
+	var (
+		help           bool
+		coordinatorURL string
+		name, token    string
+	)
+
+	flagSet := pflag.NewFlagSet("agent", pflag.ExitOnError)
+	flagSet.BoolVarP(&help, "help", "h", false, "Display help information")
+	flagSet.StringVarP(&coordinatorURL, "coordinator", "c", "", "[REQUIRED] The URL of the coordinator to register with, eg. http://10.0.0.1:7070.")
+	flagSet.StringVar(&name, "name", "", "[OPTIONAL] The name of the agent in the results of the coordinator. Defaults to agent-<n>, in the order agents register.")
+	flagSet.StringVar(&token, "token", "", "[OPTIONAL] The token of the coordinator, if it has one. Defaults to the "+tokenEnv+" environment variable.")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s agent --coordinator=<url> [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Registers with a coordinator, sends the share of the run that it assigns and streams the results back.\n")
+		fmt.Fprintf(os.Stderr, "Files named by the run flags, such as --file or --api-keys, must exist at the same path on the agent.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flagSet.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample command:\n")
+		fmt.Fprintf(os.Stderr, "  %s agent --coordinator=http://127.0.0.1:7070 --name=agent-1\n", os.Args[0])
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if help {
+		flagSet.Usage()
+		return
+	}
+	if coordinatorURL == "" {
+		exitWithUsageError(fmt.Errorf("missing required flag: -c, --coordinator for the coordinator URL"))
+	}
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+
+	/* Registration */
+	agent, err := distributed.Register(coordinatorURL, name, token)
+	if err != nil {
+		fmt.Printf("🚫 %v\n", err)
+		os.Exit(1)
+	}
+	log.LogAgentJoined(coordinatorURL, agent)
+
+	job, err := agent.Job()
+	if err != nil {
+		fmt.Printf("🚫 %v\n", err)
+		os.Exit(1)
+	}
+
+	/* Relay Util Init */
+	relayUtil, tracer, err := prepareAgent(job)
+	if err != nil {
+		fmt.Printf("🚫 Failed to prepare the job: %v\n", err)
+		if err := agent.Fail(err); err != nil {
+			fmt.Printf("🚫 %v\n", err)
+		}
+		os.Exit(1)
+	}
+	log.LogAgentJob(job)
+
+	/* Send Relays */
+	err = agent.Run(relayUtil, job)
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		fmt.Printf("🚫 Failed to flush traces: %v\n", err)
+	}
+	if err != nil {
+		fmt.Printf("🚫 %v\n", err)
+		os.Exit(1)
+	}
+
+	log.LogAgentSummary(relayUtil)
+}
+
+// prepareAgent builds the relay util of the share of the run of an agent from the run flags of its job.
+func prepareAgent(job distributed.Job) (*relay.Util, *tracing.Provider, error) {
+	flags, err := parseRunFlags(job.Args)
+	if err != nil {
+		return nil, nil, err
+	}
+	flags.executions = job.Executions
+	flags.goroutines = job.Goroutines
+
+	tracer, err := tracing.NewProvider(context.Background(), flags.tracingConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	relayConfig, err := flags.relayConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	relayConfig.IDOffset = job.IDOffset
+	relayConfig.Tracer = tracer.Tracer
+
+	relayUtil, err := relay.NewRelayUtil(relayConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return relayUtil, tracer, nil
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported values for Config.Type.
+const (
+	TypeBearer = "bearer"
+	TypeJWT    = "jwt"
+	TypeHMAC   = "hmac"
+	TypeBasic  = "basic"
+)
+
+// Defaults of the auth config.
+const (
+	DefaultRefresh    = time.Minute
+	DefaultJWTExpiry  = 5 * time.Minute
+	DefaultHMACHeader = "X-Signature"
+)
+
+type (
+	// Config configures the auth provider of a run.
+	Config struct {
+		Type string
+		// TokenFile and TokenEnv are the file or environment variable that the bearer token,
+		// the HMAC secret or the basic auth password is read from. They are read again every Refresh.
+		TokenFile, TokenEnv string
+		Refresh             time.Duration
+		// JWTKeyFile is the PEM encoded RSA or ECDSA private key that JWTs are signed
+		// with using RS256 or ES256, or a raw secret that they are signed with using HS256.
+		JWTKeyFile string
+		JWTExpiry  time.Duration
+		// JWTClaims are extra claims as a JSON object, added to the iat and exp claims.
+		JWTClaims string
+		// HMACHeader is the header carrying the HMAC signature of the request.
+		HMACHeader string
+		// Basic is the basic auth credentials, as user:password. If the password is
+		// omitted, it is read from the token file or environment variable.
+		Basic string
+	}
+
+	// Provider computes the credentials of each request.
+	Provider interface {
+		// Authenticate sets the credentials of the request, computed from its body if needed.
+		Authenticate(req *http.Request, body []byte) error
+		// String describes the provider without revealing its secrets.
+		String() string
+	}
+
+	// secretSource reads a secret from a file or an environment variable,
+	// caching it for the refresh interval.
+	secretSource struct {
+		file, env string
+		refresh   time.Duration
+
+		mu     sync.Mutex
+		secret string
+		readAt time.Time
+	}
+)
+
+// Enabled returns true if an auth provider is configured.
+func (c Config) Enabled() bool {
+	return c.Type != ""
+}
+
+// New creates the auth provider of the config.
+func New(config Config) (Provider, error) {
+	if config.Refresh <= 0 {
+		config.Refresh = DefaultRefresh
+	}
+	if config.JWTExpiry <= 0 {
+		config.JWTExpiry = DefaultJWTExpiry
+	}
+	if config.HMACHeader == "" {
+		config.HMACHeader = DefaultHMACHeader
+	}
+	if config.TokenFile != "" && config.TokenEnv != "" {
+		return nil, fmt.Errorf("only one of the token file and token environment variable may be set")
+	}
+
+	source := &secretSource{file: config.TokenFile, env: config.TokenEnv, refresh: config.Refresh}
+
+	switch strings.ToLower(config.Type) {
+	case TypeBearer:
+		if !source.configured() {
+			return nil, fmt.Errorf("bearer auth requires a token file or token environment variable")
+		}
+		return &bearer{source: source}, source.check()
+	case TypeJWT:
+		return newJWT(config)
+	case TypeHMAC:
+		if !source.configured() {
+			return nil, fmt.Errorf("hmac auth requires a secret, read from the token file or token environment variable")
+		}
+		return &hmacSigner{source: source, header: http.CanonicalHeaderKey(config.HMACHeader)}, source.check()
+	case TypeBasic:
+		user, password, hasPassword := strings.Cut(config.Basic, ":")
+		if user == "" {
+			return nil, fmt.Errorf("basic auth requires credentials as user:password")
+		}
+		if !hasPassword && !source.configured() {
+			return nil, fmt.Errorf("basic auth requires a password, either as user:password or read from the token file or token environment variable")
+		}
+		if hasPassword {
+			source = nil
+		}
+		return &basic{user: user, password: password, source: source}, source.check()
+	default:
+		return nil, fmt.Errorf("invalid auth type %q, must be %s, %s, %s or %s", config.Type, TypeBearer, TypeJWT, TypeHMAC, TypeBasic)
+	}
+}
+
+// bearer sets a bearer token read from a file or an environment variable.
+type bearer struct {
+	source *secretSource
+}
+
+func (b *bearer) Authenticate(req *http.Request, _ []byte) error {
+	token, err := b.source.get()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (b *bearer) String() string {
+	return fmt.Sprintf("bearer token from %s, refreshed every %s", b.source, b.source.refresh)
+}
+
+// basic sets basic auth credentials.
+type basic struct {
+	user, password string
+	source         *secretSource
+}
+
+func (b *basic) Authenticate(req *http.Request, _ []byte) error {
+	password := b.password
+	if b.source != nil {
+		var err error
+		if password, err = b.source.get(); err != nil {
+			return err
+		}
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(b.user + ":" + password))
+	req.Header.Set("Authorization", "Basic "+credentials)
+	return nil
+}
+
+func (b *basic) String() string {
+	if b.source != nil {
+		return fmt.Sprintf("basic auth as %s, password from %s", b.user, b.source)
+	}
+	return fmt.Sprintf("basic auth as %s", b.user)
+}
+
+// configured returns true if the source has a file or environment variable to read from.
+func (s *secretSource) configured() bool {
+	return s.file != "" || s.env != ""
+}
+
+// check reads the secret once, so that a missing secret fails before any relay is sent.
+// A nil source has nothing to check.
+func (s *secretSource) check() error {
+	if s == nil || !s.configured() {
+		return nil
+	}
+	_, err := s.get()
+	return err
+}
+
+// get returns the secret, reading it again if it is older than the refresh interval.
+// If reading it again fails, the previous secret is used until the next refresh.
+func (s *secretSource) get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secret != "" && time.Since(s.readAt) < s.refresh {
+		return s.secret, nil
+	}
+
+	secret, err := s.read()
+	s.readAt = time.Now()
+	if err != nil {
+		if s.secret != "" {
+			return s.secret, nil
+		}
+		return "", err
+	}
+	s.secret = secret
+	return s.secret, nil
+}
+
+// read reads the secret from its file or environment variable.
+func (s *secretSource) read() (string, error) {
+	if s.file != "" {
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		secret := strings.TrimSpace(string(data))
+		if secret == "" {
+			return "", fmt.Errorf("token file %s is empty", s.file)
+		}
+		return secret, nil
+	}
+
+	secret := strings.TrimSpace(os.Getenv(s.env))
+	if secret == "" {
+		return "", fmt.Errorf("environment variable %s is not set", s.env)
+	}
+	return secret, nil
+}
+
+// String describes where the secret is read from.
+func (s *secretSource) String() string {
+	if s.file != "" {
+		return "file " + s.file
+	}
+	return "env " + s.env
+}
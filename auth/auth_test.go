@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jwtSigningInput is the signing input of the example token of RFC 7519 and jwt.io,
+// with the header of the algorithm under test.
+func jwtSigningInput(algorithm string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + algorithm + `","typ":"JWT"}`))
+	return header + ".eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6IkpvaG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ"
+}
+
+// sign signs the signing input with the JWT signer of the key file.
+func sign(t *testing.T, keyFile, algorithm string) []byte {
+	t.Helper()
+	signer, err := newJWT(Config{JWTKeyFile: filepath.Join("testdata", keyFile)})
+	if err != nil {
+		t.Fatalf("newJWT() error = %v", err)
+	}
+	if signer.algorithm != algorithm {
+		t.Fatalf("algorithm = %s, want %s", signer.algorithm, algorithm)
+	}
+	signature, err := signer.sign([]byte(jwtSigningInput(algorithm)))
+	if err != nil {
+		t.Fatalf("sign() error = %v", err)
+	}
+	return signature
+}
+
+func TestJWTSignsHS256(t *testing.T) {
+	// The key file ends with a newline, which is not part of the secret
+	signature := sign(t, "hs256.key", "HS256")
+
+	// The signature of the jwt.io example token with the secret "your-256-bit-secret"
+	want := "SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c"
+	if got := base64.RawURLEncoding.EncodeToString(signature); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestJWTSignsRS256(t *testing.T) {
+	signature := sign(t, "rsa.pem", "RS256")
+
+	// The signature computed by openssl dgst -sha256 -sign testdata/rsa.pem
+	want := "LPQ8x7-Rd7tZnlj53Kv1jZQTLWtIj4vUwqfgwzf6XvTgQXMRm8ua7ntxNxkRK2gRBoC7TOFHOO_FFcqaTlrvJlt13BSL2apc8d-Eh7_YK0xzsACnEl5KpmRJyr9vxLM0vYRqyEgztsWwZ8jifqGh0GIq8-8_dlj2CiBDdt9iy3yTAn3lz2dRZX22g02D8MLvfCi2ugNazyBYWxAoTxrZx8UbjB19LAeuHdnaqebMWzCutMSTPE01VOuq5aVI36KRcxNizSyTfz9xSUi-28GOPfyKFL7zW0UZ9SIRlQM09vJW6q8mBxYe3aHg6oBuaMDfta67U2bam8q5LzHlNmR0eg"
+	if got := base64.RawURLEncoding.EncodeToString(signature); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestJWTSignsES256(t *testing.T) {
+	// ECDSA signatures are randomized, so the signature is verified against the public key instead
+	signature := sign(t, "ec.pem", "ES256")
+	if len(signature) != 64 {
+		t.Fatalf("signature is %d bytes, want the 64 bytes of r and s", len(signature))
+	}
+
+	key, err := os.ReadFile(filepath.Join("testdata", "ec.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(key)
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		t.Fatalf("parsePrivateKey() error = %v", err)
+	}
+	publicKey := &privateKey.(*ecdsa.PrivateKey).PublicKey
+
+	digest := sha256.Sum256([]byte(jwtSigningInput("ES256")))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(publicKey, digest[:], r, s) {
+		t.Error("signature does not verify against the public key")
+	}
+}
+
+func TestJWTMintsClaims(t *testing.T) {
+	signer, err := newJWT(Config{
+		JWTKeyFile: filepath.Join("testdata", "hs256.key"),
+		JWTExpiry:  time.Minute,
+		JWTClaims:  `{"sub":"relay-util"}`,
+	})
+	if err != nil {
+		t.Fatalf("newJWT() error = %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	token, err := signer.mint(now)
+	if err != nil {
+		t.Fatalf("mint() error = %v", err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts, want 3", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["sub"] != "relay-util" || claims["iat"] != float64(1700000000) || claims["exp"] != float64(1700000060) {
+		t.Errorf("claims = %v, want sub, iat and exp", claims)
+	}
+}
+
+func TestHMACSignsBody(t *testing.T) {
+	t.Setenv("RELAY_UTIL_TEST_HMAC_SECRET", "hmac-secret\n")
+	provider, err := New(Config{Type: TypeHMAC, TokenEnv: "RELAY_UTIL_TEST_HMAC_SECRET"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	signer := provider.(*hmacSigner)
+	signer.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost", nil)
+	if err := signer.Authenticate(req, body); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	// The signature computed by openssl dgst -sha256 -hmac hmac-secret over "1700000000.<body>"
+	want := "90d00c12fe586e755850b86adc50b58d6faa8610829c44ec4cc5ce853f94edbd"
+	if got := req.Header.Get(DefaultHMACHeader); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+	if got := req.Header.Get(DefaultHMACHeader + "-Timestamp"); got != "1700000000" {
+		t.Errorf("timestamp = %s, want 1700000000", got)
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwtSigner mints JWTs locally, signed with a private key or a secret, and mints a new one
+// once the current one has run through nine tenths of its expiry.
+type jwtSigner struct {
+	algorithm string
+	sign      func(signingInput []byte) ([]byte, error)
+	claims    map[string]interface{}
+	expiry    time.Duration
+	keyFile   string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// newJWT creates a JWT signer from the key file, choosing the algorithm from the type of key.
+func newJWT(config Config) (*jwtSigner, error) {
+	if config.JWTKeyFile == "" {
+		return nil, fmt.Errorf("jwt auth requires a key file")
+	}
+	key, err := os.ReadFile(config.JWTKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file: %w", err)
+	}
+
+	signer := &jwtSigner{
+		claims:  make(map[string]interface{}),
+		expiry:  config.JWTExpiry,
+		keyFile: config.JWTKeyFile,
+	}
+	if config.JWTClaims != "" {
+		if err := json.Unmarshal([]byte(config.JWTClaims), &signer.claims); err != nil {
+			return nil, fmt.Errorf("invalid JWT claims, must be a JSON object: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(key)
+	if block == nil {
+		// A key that is not PEM encoded is an HMAC secret, trimmed as token files are
+		secret := bytes.TrimSpace(key)
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("JWT key file %s is empty", config.JWTKeyFile)
+		}
+		signer.algorithm = "HS256"
+		signer.sign = func(signingInput []byte) ([]byte, error) {
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(signingInput)
+			return mac.Sum(nil), nil
+		}
+		return signer, nil
+	}
+
+	privateKey, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, err
+	}
+	switch privateKey := privateKey.(type) {
+	case *rsa.PrivateKey:
+		signer.algorithm = "RS256"
+		signer.sign = func(signingInput []byte) ([]byte, error) {
+			digest := sha256.Sum256(signingInput)
+			return rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+		}
+	case *ecdsa.PrivateKey:
+		if privateKey.Curve.Params().BitSize != 256 {
+			return nil, fmt.Errorf("unsupported ECDSA curve %s, must be P-256 for ES256", privateKey.Curve.Params().Name)
+		}
+		signer.algorithm = "ES256"
+		signer.sign = func(signingInput []byte) ([]byte, error) {
+			digest := sha256.Sum256(signingInput)
+			r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+			if err != nil {
+				return nil, err
+			}
+			// JWS encodes ECDSA signatures as the fixed size concatenation of r and s
+			signature := make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+			return signature, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT key type %T, must be RSA or ECDSA", privateKey)
+	}
+
+	return signer, nil
+}
+
+// parsePrivateKey parses a PKCS#8, PKCS#1 or SEC 1 private key.
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("failed to parse JWT key, must be a PKCS#8, PKCS#1 or SEC 1 private key")
+}
+
+func (j *jwtSigner) Authenticate(req *http.Request, _ []byte) error {
+	token, err := j.get()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (j *jwtSigner) String() string {
+	return fmt.Sprintf("%s JWT signed with %s, expiring after %s", j.algorithm, j.keyFile, j.expiry)
+}
+
+// get returns the current JWT, minting a new one if it is close to expiring.
+func (j *jwtSigner) get() (string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	if j.token != "" && now.Before(j.expiresAt.Add(-j.expiry/10)) {
+		return j.token, nil
+	}
+
+	token, err := j.mint(now)
+	if err != nil {
+		return "", fmt.Errorf("failed to mint JWT: %w", err)
+	}
+	j.token, j.expiresAt = token, now.Add(j.expiry)
+	return j.token, nil
+}
+
+// mint creates a JWT issued at now.
+func (j *jwtSigner) mint(now time.Time) (string, error) {
+	claims := make(map[string]interface{}, len(j.claims)+2)
+	for key, value := range j.claims {
+		claims[key] = value
+	}
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(j.expiry).Unix()
+
+	header, err := json.Marshal(map[string]string{"alg": j.algorithm, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := j.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,315 @@
+package chaos
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/netfault"
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// Faults injected by the chaos proxy. FaultNone is recorded for requests
+// that were forwarded untouched, apart from the added latency.
+const (
+	FaultNone     = "none"
+	FaultDrop     = "drop"
+	FaultTruncate = "truncate"
+	FaultReset    = "reset"
+)
+
+// hopHeaders are the hop-by-hop headers that are not forwarded by the proxy.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type (
+	// Config configures the faults injected by the chaos proxy.
+	// Rates are percentages between 0 and 100 and are exclusive of each other.
+	Config struct {
+		// Target is the URL whose scheme and host requests are forwarded to.
+		Target string
+		// Client sends the forwarded requests to the target.
+		Client *http.Client
+		// Latency is added to every request, varied by up to Jitter either way.
+		Latency, Jitter time.Duration
+		// DropRate is the rate of requests that are held without a response
+		// until the client gives up, as if their packets were dropped.
+		DropRate float64
+		// TruncateRate is the rate of responses whose body is cut in half.
+		TruncateRate float64
+		// ResetRate is the rate of requests whose connection is reset before they are forwarded.
+		ResetRate float64
+		// Seed seeds the random faults. 0 uses a random seed.
+		Seed int64
+	}
+
+	// Proxy is a local HTTP proxy that forwards relays to the target,
+	// injecting faults and recording them by relay ID.
+	Proxy struct {
+		config   Config
+		target   *url.URL
+		listener net.Listener
+		server   *http.Server
+
+		mu     sync.Mutex
+		rand   *rand.Rand
+		faults map[int32][]string
+	}
+
+	// Summary is the outcome of the relays into which a fault was injected.
+	// A relay is counted once per fault, even if it was injected into several of its attempts.
+	Summary struct {
+		Fault string `json:"fault"`
+		// Relays is the number of relays the fault was injected into.
+		Relays int `json:"relays"`
+		// Failed is the number of those relays that failed.
+		Failed int `json:"failed"`
+		// Recovered is the number of those relays that succeeded, after a retry.
+		Recovered int `json:"recovered"`
+		// ErrorReasons counts the failed relays by error reason.
+		ErrorReasons map[string]int `json:"error_reasons,omitempty"`
+	}
+)
+
+// Enabled returns true if the config injects any latency or fault.
+func (c Config) Enabled() bool {
+	return c.Latency > 0 || c.Jitter > 0 || c.DropRate > 0 || c.TruncateRate > 0 || c.ResetRate > 0
+}
+
+// Start validates the config and starts the chaos proxy on a local port.
+func Start(config Config) (*Proxy, error) {
+	for name, rate := range map[string]float64{
+		"drop rate":     config.DropRate,
+		"truncate rate": config.TruncateRate,
+		"reset rate":    config.ResetRate,
+	} {
+		if rate < 0 || rate > 100 {
+			return nil, fmt.Errorf("invalid %s %.2f%%, must be between 0%% and 100%%", name, rate)
+		}
+	}
+	if total := config.DropRate + config.TruncateRate + config.ResetRate; total > 100 {
+		return nil, fmt.Errorf("the drop, truncate and reset rates add up to %.2f%%, must be at most 100%%", total)
+	}
+	if config.Latency < 0 || config.Jitter < 0 {
+		return nil, fmt.Errorf("latency and jitter must be greater than or equal to 0")
+	}
+
+	target, err := url.Parse(config.Target)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid target URL %q", config.Target)
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start chaos proxy: %w", err)
+	}
+
+	proxy := &Proxy{
+		config:   config,
+		target:   target,
+		listener: listener,
+		rand:     rand.New(rand.NewSource(config.Seed)),
+		faults:   make(map[int32][]string),
+	}
+	proxy.server = &http.Server{Handler: proxy}
+	go func() { _ = proxy.server.Serve(listener) }()
+
+	return proxy, nil
+}
+
+// URL returns the URL of the proxy, to send relays through with relay.Config.Via.
+func (p *Proxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Config returns the config of the proxy, with its defaults applied.
+func (p *Proxy) Config() Config {
+	return p.config
+}
+
+// Close stops the proxy, aborting the requests it is holding.
+func (p *Proxy) Close() error {
+	return p.server.Close()
+}
+
+// Attribute records the faults injected into each relay in its result.
+func (p *Proxy) Attribute(results []relay.RelayResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range results {
+		results[i].Faults = p.faults[results[i].ID]
+	}
+}
+
+// ServeHTTP forwards a relay to the target, after adding latency and possibly injecting a fault.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	latency, fault := p.roll()
+	if id, err := strconv.ParseInt(r.Header.Get(relay.IDHeader), 10, 32); err == nil {
+		p.record(int32(id), fault)
+	}
+	r.Header.Del(relay.IDHeader)
+
+	select {
+	case <-time.After(latency):
+	case <-r.Context().Done():
+		return
+	}
+
+	switch fault {
+	case FaultReset:
+		netfault.Reset(w)
+		return
+	case FaultDrop:
+		// Hold the request until the client gives up
+		<-r.Context().Done()
+		return
+	}
+
+	resp, body, err := p.forward(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("chaos proxy: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	removeHopHeaders(w.Header())
+
+	// A truncated body keeps its full Content-Length, so the client sees an unexpected EOF
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if fault == FaultTruncate {
+		body = body[:len(body)/2]
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(body)
+}
+
+// forward sends the request to the target and reads the response body.
+func (p *Proxy) forward(r *http.Request) (*http.Response, []byte, error) {
+	target := *p.target
+	target.Path, target.RawPath, target.RawQuery = r.URL.Path, r.URL.RawPath, r.URL.RawQuery
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), r.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = r.Header.Clone()
+	removeHopHeaders(req.Header)
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// roll draws the latency and the fault of a request.
+func (p *Proxy) roll() (time.Duration, string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	latency := p.config.Latency
+	if p.config.Jitter > 0 {
+		latency += time.Duration((p.rand.Float64()*2 - 1) * float64(p.config.Jitter))
+	}
+
+	fault := FaultNone
+	roll := p.rand.Float64() * 100
+	switch {
+	case roll < p.config.DropRate:
+		fault = FaultDrop
+	case roll < p.config.DropRate+p.config.TruncateRate:
+		fault = FaultTruncate
+	case roll < p.config.DropRate+p.config.TruncateRate+p.config.ResetRate:
+		fault = FaultReset
+	}
+
+	return max(latency, 0), fault
+}
+
+// record records the fault injected into an attempt of a relay.
+func (p *Proxy) record(id int32, fault string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.faults[id] = append(p.faults[id], fault)
+}
+
+// Summarize summarizes the outcome of the relays by the faults injected into them,
+// including the relays that were forwarded untouched, in the order of FaultNone,
+// FaultDrop, FaultTruncate and FaultReset. Faults that were never injected are omitted.
+func Summarize(results []relay.RelayResult) []Summary {
+	summaries := make(map[string]*Summary)
+	for _, result := range results {
+		faults := make(map[string]bool)
+		for _, fault := range result.Faults {
+			faults[fault] = true
+		}
+		// A relay with any fault is not counted as untouched
+		if len(faults) > 1 {
+			delete(faults, FaultNone)
+		}
+
+		for fault := range faults {
+			summary, ok := summaries[fault]
+			if !ok {
+				summary = &Summary{Fault: fault, ErrorReasons: make(map[string]int)}
+				summaries[fault] = summary
+			}
+			summary.Relays++
+			switch {
+			case result.Err:
+				summary.Failed++
+				summary.ErrorReasons[result.ErrReason]++
+			case fault != FaultNone:
+				summary.Recovered++
+			}
+		}
+	}
+
+	order := map[string]int{FaultNone: 0, FaultDrop: 1, FaultTruncate: 2, FaultReset: 3}
+	list := make([]Summary, 0, len(summaries))
+	for _, summary := range summaries {
+		list = append(list, *summary)
+	}
+	sort.Slice(list, func(i, j int) bool { return order[list[i].Fault] < order[list[j].Fault] })
+	return list
+}
+
+// removeHopHeaders removes the hop-by-hop headers, which only apply to a single connection.
+func removeHopHeaders(header http.Header) {
+	for _, key := range hopHeaders {
+		header.Del(key)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/commoddity/relay-util/v2/log"
+	"github.com/commoddity/relay-util/v2/report"
+	"github.com/spf13/pflag"
+)
+
+// compareCommand compares a run report against a baseline report and logs the deltas.
+func compareCommand(args []string) {
+	var help bool
+	var tolerances report.Tolerances
+
+	flagSet := pflag.NewFlagSet("compare", pflag.ExitOnError)
+	flagSet.BoolVarP(&help, "help", "h", false, "Display help information")
+	flagSet.StringVar(&tolerances.MaxSuccessRateDrop, "max-success-rate-drop", "", "[OPTIONAL] The maximum drop of the success rate, in percentage points, eg. 1%. The program exits with code 2 if it is exceeded.")
+	flagSet.StringVar(&tolerances.MaxRPSDrop, "max-rps-drop", "", "[OPTIONAL] The maximum drop of the RPS relative to the baseline, eg. 10%. The program exits with code 2 if it is exceeded.")
+	flagSet.StringVar(&tolerances.MaxLatencyIncrease, "max-latency-increase", "", "[OPTIONAL] The maximum increase of each latency percentile relative to the baseline, eg. 20%. The program exits with code 2 if it is exceeded.")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compare [flags] <baseline.json> <current.json>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compares a report saved with --save against a baseline report and prints the change of each metric.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flagSet.PrintDefaults()
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if help {
+		flagSet.Usage()
+		return
+	}
+
+	if flagSet.NArg() != 2 {
+		exitWithUsageError(fmt.Errorf("compare requires a baseline and a current report"))
+	}
+
+	baseline, err := report.Load(flagSet.Arg(0))
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	current, err := report.Load(flagSet.Arg(1))
+	if err != nil {
+		exitWithUsageError(err)
+	}
+
+	comparison, err := report.Compare(baseline, current, tolerances)
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid tolerance: %w", err))
+	}
+
+	log.LogComparison(baseline, current, comparison)
+	if !comparison.Passed() {
+		os.Exit(exitCodeThresholdsFailed)
+	}
+}
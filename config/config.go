@@ -0,0 +1,216 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/commoddity/relay-util/v2/slo"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// File is a config file describing one or more named scenarios.
+	File struct {
+		Scenarios []Scenario `yaml:"scenarios" toml:"scenarios"`
+	}
+
+	// Scenario describes a single run of relays. Wait is measured in
+	// milliseconds and Timeout in seconds, as with the command line flags.
+	// Unset fields fall back to the command line flags and their defaults.
+	Scenario struct {
+		Name          string            `yaml:"name" toml:"name"`
+		URL           string            `yaml:"url" toml:"url"`
+		Headers       map[string]string `yaml:"headers" toml:"headers"`
+		Data          interface{}       `yaml:"data" toml:"data"`
+		Executions    int               `yaml:"executions" toml:"executions"`
+		Goroutines    int               `yaml:"goroutines" toml:"goroutines"`
+		Wait          *int              `yaml:"wait" toml:"wait"`
+		Timeout       int               `yaml:"timeout" toml:"timeout"`
+		SuccessBodies bool              `yaml:"success-bodies" toml:"success-bodies"`
+		Thresholds    slo.Spec          `yaml:"thresholds" toml:"thresholds"`
+	}
+)
+
+// envVarPattern matches ${VAR} and ${VAR:-default} references in config files.
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// Load reads a YAML or TOML config file, based on its extension, and
+// interpolates the environment variables referenced by its string values.
+func Load(path string) (*File, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file File
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &file)
+	case ".toml":
+		_, err = toml.Decode(string(raw), &file)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q, must be .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if err := file.interpolateEnv(); err != nil {
+		return nil, err
+	}
+	if err := file.validate(); err != nil {
+		return nil, err
+	}
+
+	return &file, nil
+}
+
+// Scenario returns the scenario with the given name.
+func (f *File) Scenario(name string) (*Scenario, error) {
+	for i := range f.Scenarios {
+		if f.Scenarios[i].Name == name {
+			return &f.Scenarios[i], nil
+		}
+	}
+	return nil, fmt.Errorf("scenario %q not found, must be one of: %s", name, strings.Join(f.Names(), ", "))
+}
+
+// Select returns the scenarios with the given names, in the given order,
+// or all scenarios of the file if no names are given.
+func (f *File) Select(names []string) ([]Scenario, error) {
+	if len(names) == 0 {
+		return f.Scenarios, nil
+	}
+
+	scenarios := make([]Scenario, 0, len(names))
+	for _, name := range names {
+		scenario, err := f.Scenario(name)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, *scenario)
+	}
+	return scenarios, nil
+}
+
+// Names returns the names of the scenarios in the file.
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.Scenarios))
+	for _, scenario := range f.Scenarios {
+		names = append(names, scenario.Name)
+	}
+	return names
+}
+
+// Body returns the request body of the scenario. The data may be given
+// either as a JSON string or as structured data, which is encoded as JSON.
+func (s *Scenario) Body() ([]byte, error) {
+	switch data := s.Data.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(data), nil
+	default:
+		body, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("scenario %q: failed to encode data as JSON: %w", s.Name, err)
+		}
+		return body, nil
+	}
+}
+
+// validate checks that every scenario has a unique name.
+func (f *File) validate() error {
+	if len(f.Scenarios) == 0 {
+		return fmt.Errorf("the config file has no scenarios")
+	}
+
+	seen := make(map[string]bool)
+	for i, scenario := range f.Scenarios {
+		if scenario.Name == "" {
+			return fmt.Errorf("scenario %d has no name", i+1)
+		}
+		if seen[scenario.Name] {
+			return fmt.Errorf("scenario %q is defined more than once", scenario.Name)
+		}
+		seen[scenario.Name] = true
+	}
+	return nil
+}
+
+// interpolateEnv replaces ${VAR} and ${VAR:-default} references in the string values of the scenarios
+// with the value of the environment variable. Values are interpolated after parsing, so that a value
+// containing quotes, colons or newlines cannot change the structure of the file. Unset variables without
+// a default are an error, so that a missing secret is not silently sent as an empty string.
+func (f *File) interpolateEnv() error {
+	var env interpolator
+	for i := range f.Scenarios {
+		s := &f.Scenarios[i]
+		s.Name = env.interpolate(s.Name)
+		s.URL = env.interpolate(s.URL)
+		for key, value := range s.Headers {
+			s.Headers[key] = env.interpolate(value)
+		}
+		s.Data = env.interpolateData(s.Data)
+		s.Thresholds.MaxFailureRate = env.interpolate(s.Thresholds.MaxFailureRate)
+		s.Thresholds.MaxP99 = env.interpolate(s.Thresholds.MaxP99)
+		s.Thresholds.MinRPS = env.interpolate(s.Thresholds.MinRPS)
+		for j, code := range s.Thresholds.MaxErrorCodes {
+			s.Thresholds.MaxErrorCodes[j] = env.interpolate(code)
+		}
+	}
+
+	if len(env.missing) > 0 {
+		return fmt.Errorf("environment variables referenced in the config file are not set: %s", strings.Join(env.missing, ", "))
+	}
+	return nil
+}
+
+// interpolator interpolates environment variables and collects the unset ones.
+type interpolator struct {
+	missing []string
+}
+
+// interpolate replaces the environment variable references of a value.
+func (e *interpolator) interpolate(value string) string {
+	return envVarPattern.ReplaceAllStringFunc(value, func(match string) string {
+		groups := envVarPattern.FindStringSubmatch(match)
+		if value, ok := os.LookupEnv(groups[1]); ok {
+			return value
+		}
+		if groups[2] != "" {
+			return groups[3]
+		}
+		if !slices.Contains(e.missing, groups[1]) {
+			e.missing = append(e.missing, groups[1])
+		}
+		return match
+	})
+}
+
+// interpolateData interpolates the string values of structured request data, at any depth.
+func (e *interpolator) interpolateData(data interface{}) interface{} {
+	switch data := data.(type) {
+	case string:
+		return e.interpolate(data)
+	case map[string]interface{}:
+		for key, value := range data {
+			data[key] = e.interpolateData(value)
+		}
+	case []interface{}:
+		for i, value := range data {
+			data[i] = e.interpolateData(value)
+		}
+	case []map[string]interface{}:
+		for _, value := range data {
+			e.interpolateData(value)
+		}
+	}
+	return data
+}
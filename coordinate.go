@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/corpus"
+	"github.com/commoddity/relay-util/v2/distributed"
+	"github.com/commoddity/relay-util/v2/log"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/slo"
+	"github.com/spf13/pflag"
+)
+
+// tokenEnv is the environment variable that the token of a distributed run is read from,
+// when --token is not set, so that it does not show in the process list.
+const tokenEnv = "RELAY_UTIL_TOKEN"
+
+// coordinateCommand splits a run across agents, waits for their results and logs them as a single run.
+func coordinateCommand(args []string) {
+	var (
+		help                     bool
+		listen, token            string
+		agents                   int
+		startDelay, agentTimeout time.Duration
+		registerTimeout          time.Duration
+	)
+
+	flagSet := pflag.NewFlagSet("coordinate", pflag.ExitOnError)
+	flagSet.BoolVarP(&help, "help", "h", false, "Display help information")
+	flagSet.IntVarP(&agents, "agents", "n", 0, "[REQUIRED] The number of agents that the run is split across. It starts once they have all registered.")
+	flagSet.StringVarP(&listen, "listen", "l", "127.0.0.1:7070", "[OPTIONAL] The address that agents connect to. Addresses other than loopback require a token.")
+	flagSet.StringVar(&token, "token", "", "[OPTIONAL] The shared secret that agents must send with every request, as the run flags they receive may carry credentials. Defaults to the "+tokenEnv+" environment variable. Required unless listening on a loopback address.")
+	flagSet.DurationVar(&startDelay, "start-delay", distributed.DefaultStartDelay, "[OPTIONAL] The time between the last agent registering and all agents starting, for them to prepare.")
+	flagSet.DurationVar(&agentTimeout, "agent-timeout", distributed.DefaultAgentTimeout, "[OPTIONAL] How long a running agent may go without sending results before the run fails.")
+	flagSet.DurationVar(&registerTimeout, "register-timeout", distributed.DefaultRegisterTimeout, "[OPTIONAL] How long to wait for all agents to register before the run fails.")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s coordinate [flags] -- <run flags>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Splits a run across relay-util agents, which start at the same time and stream their results back,\n")
+		fmt.Fprintf(os.Stderr, "and logs and reports their merged results as a single run. The flags after -- are those of the run command.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flagSet.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample command:\n")
+		fmt.Fprintf(os.Stderr, "  %s coordinate --agents=3 -- -u=https://path.rpc.grove.city/v1 -H=\"target-service-id: F00C\" -x=30000 -g=300\n", os.Args[0])
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if help {
+		flagSet.Usage()
+		return
+	}
+	if agents < 1 {
+		exitWithUsageError(fmt.Errorf("missing required flag: -n, --agents for the number of agents"))
+	}
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	runArgs := flagSet.Args()
+	if len(runArgs) == 0 {
+		exitWithUsageError(fmt.Errorf("missing the run flags after --"))
+	}
+
+	/* Run Flags */
+	flags, err := parseRunFlags(runArgs)
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	if err := validateDistributedFlags(flags); err != nil {
+		exitWithUsageError(err)
+	}
+	thresholds, err := flags.slo.Thresholds()
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid SLO threshold: %w", err))
+	}
+
+	// The relay util of the coordinator sends no relays, but holds the merged results of the agents
+	relayConfig, err := flags.relayConfig()
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	relayUtil, err := relay.NewRelayUtil(relayConfig)
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	/* Coordinator Init */
+	coordinator, err := distributed.Start(distributed.Config{
+		Listen:          listen,
+		Token:           token,
+		Agents:          agents,
+		Args:            runArgs,
+		Executions:      relayConfig.Executions,
+		Goroutines:      relayConfig.Goroutines,
+		StartDelay:      startDelay,
+		AgentTimeout:    agentTimeout,
+		RegisterTimeout: registerTimeout,
+		OnRegister:      func(agent distributed.AgentSummary) { log.LogAgentRegistered(agent, agents) },
+		OnDone:          log.LogAgentDone,
+	})
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	defer coordinator.Close()
+
+	log.PrintConfig(relayUtil)
+	log.PrintCoordinator(coordinator.URL(), agents)
+
+	/* Merge Results */
+	result, err := coordinator.Wait()
+	if err != nil {
+		fmt.Printf("🚫 Distributed run failed: %v\n", err)
+		os.Exit(1)
+	}
+	relayUtil.SetResults(result.Results, result.ExecTime)
+
+	log.LogResults(relayUtil)
+	log.LogAgents(result.Agents)
+	saveReports(relayUtil, flags.savePath, flags.htmlPath)
+
+	if len(thresholds) > 0 {
+		results := slo.Check(thresholds, relayUtil.Stats())
+		log.LogThresholds(results)
+		if !slo.Passed(results) {
+			os.Exit(exitCodeThresholdsFailed)
+		}
+	}
+}
+
+// validateDistributedFlags returns an error for run flags that need the whole run in a single process.
+func validateDistributedFlags(flags runFlags) error {
+	chaosConfig, err := flags.chaosConfig()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case flags.findMax:
+		return fmt.Errorf("--find-max cannot be used in a distributed run")
+	case flags.recordPath != "":
+		return fmt.Errorf("--record cannot be used in a distributed run")
+	case flags.capture.Enabled():
+		return fmt.Errorf("--capture-slowest and --capture-failed cannot be used in a distributed run")
+	case chaosConfig.Enabled():
+		return fmt.Errorf("chaos flags cannot be used in a distributed run")
+	case flags.importPath != "" && flags.importMode == corpus.ModeStream:
+		return fmt.Errorf("--import-mode=%s cannot be used in a distributed run", corpus.ModeStream)
+	}
+	return nil
+}
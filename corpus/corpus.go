@@ -0,0 +1,161 @@
+package corpus
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/record"
+	"github.com/commoddity/relay-util/v2/redact"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/seeded"
+)
+
+// Supported values of the import mode.
+const (
+	// ModeCorpus sends the imported requests at random, weighted by how often they were imported.
+	ModeCorpus = "corpus"
+	// ModeStream replays the imported requests in order, with their original timing.
+	ModeStream = "stream"
+)
+
+// maxMethodsDescribed is the number of JSON-RPC methods listed in the description of a corpus.
+const maxMethodsDescribed = 5
+
+// browserHeaders are the headers set by the browser that made an imported request,
+// which describe the page it was made from rather than the request.
+var browserHeaders = []string{"Cookie", "Origin", "Referer", "Priority", "Upgrade-Insecure-Requests", "Dnt"}
+
+type (
+	// Corpus is a relay.Source sending imported requests at random, weighted by how often
+	// they were imported, so that the method mix of real traffic is reproduced.
+	Corpus struct {
+		requests []relay.Request
+		// cumulative are the cumulative weights of the requests.
+		cumulative []int
+		seed       uint64
+		methods    []MethodShare
+	}
+
+	// MethodShare is the share of a JSON-RPC method in imported traffic, as a percentage.
+	MethodShare struct {
+		Method string
+		Share  float64
+	}
+)
+
+// New creates a weighted corpus of the imported requests. Each relay picks its request
+// from the seed and its ID, so that runs with the same seed send the same requests.
+// A seed of 0 uses a random seed.
+func New(entries []record.Entry, seed int64) *Corpus {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	// Requests that only differ by their timing are the same request, weighted by their count
+	var distinct []record.Entry
+	var weights []int
+	index := make(map[string]int)
+	for _, entry := range entries {
+		key := entry.RequestBody + "\x00" + fmt.Sprint(entry.RequestHeaders)
+		if i, ok := index[key]; ok {
+			weights[i]++
+			continue
+		}
+		index[key] = len(distinct)
+		distinct = append(distinct, entry)
+		weights = append(weights, 1)
+	}
+
+	source := record.NewSource(distinct, 0)
+	c := &Corpus{seed: uint64(seed), methods: Methods(entries)}
+	total := 0
+	for i, weight := range weights {
+		total += weight
+		c.requests = append(c.requests, source.Request(int32(i+1)))
+		c.cumulative = append(c.cumulative, total)
+	}
+	return c
+}
+
+// Request returns the request of the relay, picked at random by weight.
+func (c *Corpus) Request(id int32) relay.Request {
+	total := c.cumulative[len(c.cumulative)-1]
+	pick := int(seeded.Hash(c.seed^uint64(id)) % uint64(total))
+	i := sort.SearchInts(c.cumulative, pick+1)
+	return c.requests[i]
+}
+
+// String describes the corpus and its method mix.
+func (c *Corpus) String() string {
+	total := c.cumulative[len(c.cumulative)-1]
+	return fmt.Sprintf("weighted corpus of %d distinct requests out of %d imported (%s)", len(c.requests), total, describeMethods(c.methods))
+}
+
+// Sanitize strips the credentials of imported requests before they are sent:
+// the sensitive headers and the headers of the browser that made them are removed,
+// and secrets in the URLs and bodies are masked, following the redaction rules.
+func Sanitize(entries []record.Entry) []record.Entry {
+	sanitized := make([]record.Entry, len(entries))
+	for i, entry := range entries {
+		headers := entry.RequestHeaders.Clone()
+		for key := range headers {
+			if redact.IsSensitiveHeader(key) || isBrowserHeader(key) {
+				headers.Del(key)
+			}
+		}
+		entry.RequestHeaders = headers
+		entry.URL = redact.URL(entry.URL)
+		entry.RequestBody = redact.Body(entry.RequestBody)
+		sanitized[i] = entry
+	}
+	return sanitized
+}
+
+// Methods returns the share of each JSON-RPC method in the requests, the most frequent first.
+// The methods of a batch are counted separately.
+func Methods(entries []record.Entry) []MethodShare {
+	counts := make(map[string]int)
+	total := 0
+	for _, entry := range entries {
+		for _, method := range record.Methods(entry.RequestBody) {
+			counts[method]++
+			total++
+		}
+	}
+
+	shares := make([]MethodShare, 0, len(counts))
+	for method, count := range counts {
+		shares = append(shares, MethodShare{Method: method, Share: float64(count) / float64(total) * 100})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share != shares[j].Share {
+			return shares[i].Share > shares[j].Share
+		}
+		return shares[i].Method < shares[j].Method
+	})
+	return shares
+}
+
+// isBrowserHeader reports whether the header was set by the browser that made the request.
+// Sec- headers, such as Sec-Fetch-Mode and Sec-Ch-Ua, can only be set by browsers.
+func isBrowserHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	return strings.HasPrefix(key, "Sec-") || slices.Contains(browserHeaders, key)
+}
+
+// describeMethods lists the most frequent methods with their shares.
+func describeMethods(shares []MethodShare) string {
+	parts := make([]string, 0, maxMethodsDescribed+1)
+	for i, share := range shares {
+		if i == maxMethodsDescribed {
+			parts = append(parts, fmt.Sprintf("%d more", len(shares)-maxMethodsDescribed))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f%%", share.Method, share.Share))
+	}
+	return strings.Join(parts, ", ")
+}
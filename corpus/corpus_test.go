@@ -0,0 +1,39 @@
+package corpus
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/commoddity/relay-util/v2/record"
+)
+
+func TestSanitizeRemovesBrowserHeaders(t *testing.T) {
+	entries := []record.Entry{{
+		RequestHeaders: http.Header{
+			"Authorization":     {"Bearer secret"},
+			"Cookie":            {"session=abc123"},
+			"Origin":            {"https://app.example.com"},
+			"Referer":           {"https://app.example.com/wallet"},
+			"Sec-Fetch-Mode":    {"cors"},
+			"Sec-Ch-Ua":         {`"Chromium";v="124"`},
+			"Content-Type":      {"application/json"},
+			"Target-Service-Id": {"eth"},
+		},
+		RequestBody: `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`,
+	}}
+
+	headers := Sanitize(entries)[0].RequestHeaders
+	for _, key := range []string{"Authorization", "Cookie", "Origin", "Referer", "Sec-Fetch-Mode", "Sec-Ch-Ua"} {
+		if value := headers.Get(key); value != "" {
+			t.Errorf("%s = %q, want it removed", key, value)
+		}
+	}
+	for _, key := range []string{"Content-Type", "Target-Service-Id"} {
+		if headers.Get(key) == "" {
+			t.Errorf("%s was removed, want it kept", key)
+		}
+	}
+	if entries[0].RequestHeaders.Get("Cookie") == "" {
+		t.Error("Sanitize() changed the headers of the imported entries")
+	}
+}
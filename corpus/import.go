@@ -0,0 +1,241 @@
+package corpus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/record"
+)
+
+// ServiceIDHeader is the header that the service ID of an access log line is sent in.
+const ServiceIDHeader = "Target-Service-Id"
+
+// The fields of an access log line that the request is read from, in order of preference.
+// Dotted fields are nested, eg. request.body is the body field of the request object.
+var (
+	bodyFields      = []string{"request_body", "requestBody", "request.body", "req_body", "body", "payload", "request"}
+	headerFields    = []string{"request_headers", "requestHeaders", "request.headers", "headers"}
+	serviceIDFields = []string{"service_id", "serviceId", "serviceID", "target_service_id", "target-service-id", "request.service_id"}
+	timeFields      = []string{"started_at", "startedAt", "timestamp", "@timestamp", "time", "ts", "start_time", "time_iso8601", "time_local"}
+)
+
+// timeLayouts are the layouts of timestamps logged as text: RFC 3339, RFC 3339 with
+// an offset without a colon, and the time_local format of nginx and Apache.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700", "02/Jan/2006:15:04:05 -0700"}
+
+// Load reads the JSON-RPC requests of a HAR file, a recording or a JSON access log,
+// in the order they were sent. Access logs are either a JSON array or one JSON object
+// per line; lines that are not JSON or have no JSON-RPC request are skipped, as are
+// recorded requests whose bodies were changed by redaction.
+func Load(path string) ([]record.Entry, error) {
+	var entries []record.Entry
+	if record.Format(path) == record.FormatHAR {
+		var err error
+		if entries, err = record.Load(path); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read access log: %w", err)
+		}
+		if entries, err = parseAccessLog(data); err != nil {
+			return nil, fmt.Errorf("failed to read access log %s: %w", path, err)
+		}
+	}
+
+	// Request bodies changed by redaction are not the requests that were sent
+	entries, _ = record.Replayable(entries)
+	requests := make([]record.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if isJSONRPC(entry.RequestBody) {
+			requests = append(requests, entry)
+		}
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("%s has no JSON-RPC requests", path)
+	}
+	record.SortEntries(requests)
+	return requests, nil
+}
+
+// parseAccessLog reads the lines of a JSON access log, as a JSON array or one JSON object per line.
+func parseAccessLog(data []byte) ([]record.Entry, error) {
+	var lines []map[string]any
+	// numbers are the line numbers of the lines, or their positions in a JSON array
+	var numbers []int
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &lines); err != nil {
+			return nil, err
+		}
+		for i := range lines {
+			numbers = append(numbers, i+1)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for number := 1; scanner.Scan(); number++ {
+			var line map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &line); err == nil {
+				lines = append(lines, line)
+				numbers = append(numbers, number)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]record.Entry, 0, len(lines))
+	for i, line := range lines {
+		entry := record.Entry{
+			ID:             int32(i + 1),
+			Method:         http.MethodPost,
+			RequestHeaders: make(http.Header),
+		}
+		if value, ok := lookup(line, bodyFields); ok {
+			entry.RequestBody = jsonText(value)
+		}
+		if value, ok := lookup(line, headerFields); ok {
+			addHeaders(entry.RequestHeaders, value)
+		}
+		if value, ok := lookup(line, serviceIDFields); ok && entry.RequestHeaders.Get(ServiceIDHeader) == "" {
+			if serviceID, ok := value.(string); ok && serviceID != "" {
+				entry.RequestHeaders.Set(ServiceIDHeader, serviceID)
+			}
+		}
+		if redacted, ok := line["request_body_redacted"].(bool); ok {
+			entry.RequestBodyRedacted = redacted
+		}
+		if value, ok := lookup(line, timeFields); ok {
+			startedAt, err := parseTime(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", numbers[i], err)
+			}
+			entry.StartedAt = startedAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// lookup returns the value of the first of the fields that the line has.
+func lookup(line map[string]any, fields []string) (any, bool) {
+	for _, field := range fields {
+		var value any = line
+		for _, key := range strings.Split(field, ".") {
+			object, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+			value = object[key]
+		}
+		if value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// jsonText returns a body logged as a JSON string as it is, and a body logged as JSON as its text.
+func jsonText(value any) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// addHeaders adds headers logged as an object of strings or of lists of strings.
+func addHeaders(headers http.Header, value any) {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return
+	}
+	for key, values := range object {
+		switch v := values.(type) {
+		case string:
+			headers.Add(key, v)
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					headers.Add(key, s)
+				}
+			}
+		}
+	}
+}
+
+// parseTime parses a timestamp logged as text in one of the time layouts, or as a Unix time
+// in seconds, milliseconds, microseconds or nanoseconds, either as a number or as text.
+func parseTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		text := strings.TrimSpace(v)
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, nil
+			}
+		}
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return unixTime(number), nil
+		}
+	case float64:
+		return unixTime(v), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %v, must be RFC 3339, nginx time_local or a Unix time", value)
+}
+
+// unixTime returns the time of a Unix time in seconds, milliseconds, microseconds or nanoseconds,
+// told apart by their magnitude.
+func unixTime(v float64) time.Time {
+	switch {
+	case v >= 1e17:
+		return time.Unix(0, int64(v))
+	case v >= 1e14:
+		return time.UnixMicro(int64(v))
+	case v >= 1e11:
+		return time.UnixMilli(int64(v))
+	default:
+		seconds, fraction := math.Modf(v)
+		return time.Unix(int64(seconds), int64(fraction*1e9))
+	}
+}
+
+// CheckTimestamps returns an error if only some of the entries have the time they were sent,
+// as their timing cannot then be replayed.
+func CheckTimestamps(entries []record.Entry) error {
+	untimed := 0
+	for _, entry := range entries {
+		if entry.StartedAt.IsZero() {
+			untimed++
+		}
+	}
+	if untimed > 0 && untimed < len(entries) {
+		return fmt.Errorf("%d of %d imported requests have no time, so their timing cannot be replayed", untimed, len(entries))
+	}
+	return nil
+}
+
+// isJSONRPC returns true if the body is a JSON-RPC request or a batch of them.
+func isJSONRPC(body string) bool {
+	methods := record.Methods(body)
+	for _, method := range methods {
+		if method == "" {
+			return false
+		}
+	}
+	return len(methods) > 0
+}
@@ -0,0 +1,71 @@
+package corpus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/record"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(2023, 10, 11, 16, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   any
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "RFC 3339", value: "2023-10-11T16:00:00Z", want: want},
+		{name: "RFC 3339 with offset", value: "2023-10-11T18:00:00+02:00", want: want},
+		{name: "offset without colon", value: "2023-10-11T18:00:00.000+0200", want: want},
+		{name: "nginx time_local", value: "11/Oct/2023:18:00:00 +0200", want: want},
+		{name: "seconds", value: float64(1697040000), want: want},
+		{name: "milliseconds", value: float64(1697040000000), want: want},
+		{name: "numeric string", value: "1697040000.5", want: want.Add(500 * time.Millisecond)},
+		{name: "unknown layout", value: "Oct 11 2023 16:00", wantErr: true},
+		{name: "boolean", value: true, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseTime(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseTime(%v) error = %v, want error %t", test.value, err, test.wantErr)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("parseTime(%v) = %s, want %s", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseAccessLogRejectsInvalidTimes(t *testing.T) {
+	log := `{"time":"2023-10-11T16:00:00Z","body":{"jsonrpc":"2.0","id":1,"method":"eth_chainId"}}
+not json
+{"time":"yesterday","body":{"jsonrpc":"2.0","id":2,"method":"eth_chainId"}}
+`
+	_, err := parseAccessLog([]byte(log))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("parseAccessLog() error = %v, want an invalid time on line 3", err)
+	}
+}
+
+func TestCheckTimestamps(t *testing.T) {
+	timed := record.Entry{StartedAt: time.Unix(1697040000, 0)}
+	tests := []struct {
+		name    string
+		entries []record.Entry
+		wantErr bool
+	}{
+		{name: "all timed", entries: []record.Entry{timed, timed}},
+		{name: "none timed", entries: []record.Entry{{}, {}}},
+		{name: "some timed", entries: []record.Entry{timed, {}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckTimestamps(test.entries); (err != nil) != test.wantErr {
+				t.Errorf("CheckTimestamps() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package distributed
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// requestTimeout is the timeout of the requests of an agent to the coordinator,
+// except for the request of its job, which waits for all agents to register.
+const requestTimeout = 10 * time.Second
+
+// Agent is an agent registered with a coordinator, which sends its share of the run.
+type Agent struct {
+	ID          string
+	coordinator string
+	token       string
+	client      *http.Client
+	jobClient   *http.Client
+}
+
+// Register registers an agent with the coordinator at the URL, eg. http://127.0.0.1:7070,
+// with the token of the run, if it has one.
+func Register(coordinator, name, token string) (*Agent, error) {
+	if _, err := url.ParseRequestURI(coordinator); err != nil {
+		return nil, fmt.Errorf("invalid coordinator URL %q: %w", coordinator, err)
+	}
+
+	a := &Agent{
+		coordinator: strings.TrimSuffix(coordinator, "/"),
+		token:       token,
+		client:      &http.Client{Timeout: requestTimeout},
+		jobClient:   &http.Client{},
+	}
+	var response registered
+	if err := post(a.client, a.coordinator+registerPath, a.token, registration{Name: name}, &response); err != nil {
+		return nil, fmt.Errorf("failed to register with the coordinator: %w", err)
+	}
+	a.ID = response.ID
+	return a, nil
+}
+
+// Job waits for all agents to register and returns the job of the agent,
+// with its start time on the clock of the agent.
+func (a *Agent) Job() (Job, error) {
+	req, err := newRequest(http.MethodGet, a.url(jobPath), a.token, nil)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to get the job: %w", err)
+	}
+	resp, err := a.jobClient.Do(req)
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to get the job: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var job Job
+	if err := decodeResponse(resp, &job); err != nil {
+		return Job{}, fmt.Errorf("failed to get the job: %w", err)
+	}
+	job.StartAt = time.Now().Add(job.StartIn)
+	return job, nil
+}
+
+// Run waits for the start time of the job, sends the relays of the agent and streams
+// their results to the coordinator as they complete. The results are also kept in the
+// relay util, so that the agent can log them.
+func (a *Agent) Run(u *relay.Util, job Job) error {
+	time.Sleep(time.Until(job.StartAt))
+
+	streamed := make(chan error, 1)
+	go func() { streamed <- a.stream(u) }()
+	u.SendRelays()
+	err := <-streamed
+
+	report := done{ExecTime: u.ExecTime}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return errors.Join(err, a.done(report))
+}
+
+// Fail reports to the coordinator that the agent could not run its job.
+func (a *Agent) Fail(err error) error {
+	return a.done(done{Error: err.Error()})
+}
+
+// stream sends the results of the relays to the coordinator in batches, every heartbeat
+// interval, until all relays are sent. A batch is sent even if it is empty, as a heartbeat.
+func (a *Agent) stream(u *relay.Util) error {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	var batch []relay.RelayResult
+	for {
+		select {
+		case result, ok := <-u.ResultChan:
+			if !ok {
+				return a.send(batch)
+			}
+			batch = append(batch, result)
+			u.Results = append(u.Results, result)
+		case <-ticker.C:
+			if err := a.send(batch); err != nil {
+				return err
+			}
+			batch = nil
+		}
+	}
+}
+
+// send sends a batch of results to the coordinator.
+func (a *Agent) send(batch []relay.RelayResult) error {
+	if batch == nil {
+		batch = []relay.RelayResult{}
+	}
+	if err := post(a.client, a.url(resultsPath), a.token, batch, nil); err != nil {
+		return fmt.Errorf("failed to send results: %w", err)
+	}
+	return nil
+}
+
+// done reports to the coordinator that the agent is done.
+func (a *Agent) done(report done) error {
+	if err := post(a.client, a.url(donePath), a.token, report, nil); err != nil {
+		return fmt.Errorf("failed to report to the coordinator: %w", err)
+	}
+	return nil
+}
+
+// url returns the URL of an endpoint of the coordinator for the agent.
+func (a *Agent) url(path string) string {
+	return a.coordinator + path + "?" + url.Values{agentParam: {a.ID}}.Encode()
+}
@@ -0,0 +1,397 @@
+package distributed
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// Defaults of the coordinator config.
+const (
+	DefaultStartDelay      = 2 * time.Second
+	DefaultAgentTimeout    = 30 * time.Second
+	DefaultRegisterTimeout = 5 * time.Minute
+)
+
+type (
+	// Config configures a coordinator splitting a run across agents.
+	Config struct {
+		// Listen is the address that agents connect to, eg. 127.0.0.1:7070.
+		Listen string
+		// Token is the shared secret that agents send with every request, as the run flags
+		// they are sent may carry credentials. It is required unless Listen is a loopback address.
+		Token string
+		// Agents is the number of agents that the run is split across. It starts once they have all registered.
+		Agents int
+		// Args are the run flags sent to every agent.
+		Args []string
+		// Executions and Goroutines are split as evenly as possible across the agents.
+		Executions int
+		Goroutines int
+		// StartDelay is the time between the last agent registering and all agents starting, for them to prepare.
+		StartDelay time.Duration
+		// AgentTimeout is how long a running agent may go without sending results before the run fails.
+		AgentTimeout time.Duration
+		// RegisterTimeout is how long the coordinator waits for all agents to register before the run fails.
+		RegisterTimeout time.Duration
+		// OnRegister and OnDone are called when an agent registers and when it is done, if set.
+		OnRegister func(AgentSummary)
+		OnDone     func(AgentSummary)
+	}
+
+	// AgentSummary is an agent registered with the coordinator and its share of the run.
+	AgentSummary struct {
+		ID         string        `json:"id"`
+		Name       string        `json:"name"`
+		Executions int           `json:"executions"`
+		Goroutines int           `json:"goroutines"`
+		Relays     int           `json:"relays"`
+		Failed     int           `json:"failed"`
+		ExecTime   time.Duration `json:"exec_time"`
+		Error      string        `json:"error,omitempty"`
+	}
+
+	// Result is the merged result of the agents of a run.
+	Result struct {
+		// Results are the results of the relays of all agents, by relay ID.
+		Results []relay.RelayResult
+		// ExecTime is the execution time of the slowest agent, as all agents start at once.
+		ExecTime time.Duration
+		Agents   []AgentSummary
+	}
+
+	// Coordinator waits for agents to register, assigns them their share of the run,
+	// and merges the results they stream back.
+	Coordinator struct {
+		config   Config
+		started  time.Time
+		listener net.Listener
+		server   *http.Server
+
+		mu       sync.Mutex
+		agents   []*agentState
+		results  []relay.RelayResult
+		ready    chan struct{}
+		finished chan struct{}
+	}
+
+	// agentState is the progress of a registered agent.
+	agentState struct {
+		AgentSummary
+		job      Job
+		lastSeen time.Time
+		done     bool
+	}
+)
+
+// Start validates the config and starts listening for agents.
+func Start(config Config) (*Coordinator, error) {
+	if config.Agents < 1 {
+		return nil, fmt.Errorf("agents must be greater than 0")
+	}
+	if config.Executions < config.Agents {
+		return nil, fmt.Errorf("executions must be at least the number of agents")
+	}
+	if config.Goroutines < config.Agents {
+		return nil, fmt.Errorf("goroutines must be at least the number of agents")
+	}
+	if config.StartDelay <= 0 {
+		config.StartDelay = DefaultStartDelay
+	}
+	if config.AgentTimeout <= 0 {
+		config.AgentTimeout = DefaultAgentTimeout
+	}
+	if config.RegisterTimeout <= 0 {
+		config.RegisterTimeout = DefaultRegisterTimeout
+	}
+
+	if config.Token == "" && !isLoopback(config.Listen) {
+		return nil, fmt.Errorf("a token is required to listen on %s, which is not a loopback address", config.Listen)
+	}
+
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for agents: %w", err)
+	}
+
+	c := &Coordinator{
+		config:   config,
+		started:  time.Now(),
+		listener: listener,
+		ready:    make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(registerPath, c.handleRegister)
+	mux.HandleFunc(jobPath, c.handleJob)
+	mux.HandleFunc(resultsPath, c.handleResults)
+	mux.HandleFunc(donePath, c.handleDone)
+	c.server = &http.Server{Handler: c.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = c.server.Serve(listener) }()
+	return c, nil
+}
+
+// URL returns the URL that agents connect to.
+func (c *Coordinator) URL() string {
+	return "http://" + c.listener.Addr().String()
+}
+
+// Close stops listening for agents.
+func (c *Coordinator) Close() error {
+	return c.server.Close()
+}
+
+// Wait waits for all agents to register and send all of their relays, and merges their results.
+// It fails if not all agents register within the register timeout, or if an agent fails or
+// stops sending results for longer than the agent timeout.
+func (c *Coordinator) Wait() (*Result, error) {
+	deadline := time.NewTimer(time.Until(c.started.Add(c.config.RegisterTimeout)))
+	defer deadline.Stop()
+	select {
+	case <-c.ready:
+	case <-deadline.C:
+		c.mu.Lock()
+		registered := len(c.agents)
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%d of %d agents registered within %s", registered, c.config.Agents, c.config.RegisterTimeout)
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.finished:
+			return c.result()
+		case <-ticker.C:
+			if err := c.checkTimeouts(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// checkTimeouts returns an error if a running agent has not been heard from within the agent timeout.
+func (c *Coordinator) checkTimeouts() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, agent := range c.agents {
+		if agent.done || time.Now().Before(agent.job.StartAt) {
+			continue
+		}
+		if silence := time.Since(agent.lastSeen); silence > c.config.AgentTimeout {
+			return fmt.Errorf("agent %s sent no results for %s", agent.Name, silence.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// result merges the results of the agents, once they are all done.
+func (c *Coordinator) result() (*Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := &Result{Results: c.results}
+	sort.Slice(result.Results, func(i, j int) bool { return result.Results[i].ID < result.Results[j].ID })
+	var errs []error
+	for _, agent := range c.agents {
+		result.Agents = append(result.Agents, agent.AgentSummary)
+		result.ExecTime = max(result.ExecTime, agent.ExecTime)
+		if agent.Error != "" {
+			errs = append(errs, fmt.Errorf("agent %s failed: %s", agent.Name, agent.Error))
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// handleRegister registers an agent, and assigns every agent its job once they have all registered.
+func (c *Coordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var request registration
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&request) != nil {
+		http.Error(w, "expected a JSON registration", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.agents) == c.config.Agents {
+		http.Error(w, fmt.Sprintf("all %d agents of the run have registered", c.config.Agents), http.StatusConflict)
+		return
+	}
+
+	id := fmt.Sprintf("%d", len(c.agents)+1)
+	name := request.Name
+	if name == "" {
+		name = "agent-" + id
+	}
+	agent := &agentState{AgentSummary: AgentSummary{ID: id, Name: name}}
+	c.agents = append(c.agents, agent)
+	if c.config.OnRegister != nil {
+		c.config.OnRegister(agent.AgentSummary)
+	}
+	if len(c.agents) == c.config.Agents {
+		c.assignJobs()
+		close(c.ready)
+	}
+	writeJSON(w, registered{ID: id})
+}
+
+// assignJobs splits the relays and goroutines of the run as evenly as possible across the agents,
+// which all start after the start delay.
+func (c *Coordinator) assignJobs() {
+	startAt := time.Now().Add(c.config.StartDelay)
+	agents := len(c.agents)
+	var offset int32
+	for i, agent := range c.agents {
+		executions := c.config.Executions / agents
+		if i < c.config.Executions%agents {
+			executions++
+		}
+		goroutines := c.config.Goroutines / agents
+		if i < c.config.Goroutines%agents {
+			goroutines++
+		}
+		agent.Executions = executions
+		agent.Goroutines = goroutines
+		agent.job = Job{
+			Args:       c.config.Args,
+			Executions: agent.Executions,
+			Goroutines: agent.Goroutines,
+			IDOffset:   offset,
+			StartAt:    startAt,
+		}
+		agent.lastSeen = startAt
+		offset += int32(executions)
+	}
+}
+
+// handleJob responds with the job of the agent, once all agents have registered.
+func (c *Coordinator) handleJob(w http.ResponseWriter, r *http.Request) {
+	select {
+	case <-c.ready:
+	case <-r.Context().Done():
+		return
+	}
+
+	agent, ok := c.agent(w, r)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	job := agent.job
+	c.mu.Unlock()
+	job.StartIn = time.Until(job.StartAt)
+	writeJSON(w, job)
+}
+
+// handleResults merges a batch of results streamed by an agent.
+func (c *Coordinator) handleResults(w http.ResponseWriter, r *http.Request) {
+	agent, ok := c.agent(w, r)
+	if !ok {
+		return
+	}
+	var results []relay.RelayResult
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&results) != nil {
+		http.Error(w, "expected a JSON array of results", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, results...)
+	agent.Relays += len(results)
+	for _, result := range results {
+		if result.Err {
+			agent.Failed++
+		}
+	}
+	agent.lastSeen = time.Now()
+}
+
+// handleDone marks an agent as done, and the run as finished once all agents are done.
+func (c *Coordinator) handleDone(w http.ResponseWriter, r *http.Request) {
+	agent, ok := c.agent(w, r)
+	if !ok {
+		return
+	}
+	var request done
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&request) != nil {
+		http.Error(w, "expected a JSON report", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if agent.done {
+		return
+	}
+	agent.done = true
+	agent.ExecTime = request.ExecTime
+	agent.Error = request.Error
+	agent.lastSeen = time.Now()
+	if c.config.OnDone != nil {
+		c.config.OnDone(agent.AgentSummary)
+	}
+	for _, a := range c.agents {
+		if !a.done {
+			return
+		}
+	}
+	close(c.finished)
+}
+
+// authorize rejects requests without the token of the run, if it has one.
+func (c *Coordinator) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), bearerPrefix)
+		if c.config.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.config.Token)) != 1 {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopback returns true if the listen address only accepts connections from the same machine.
+// An address without a host listens on every interface.
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// agent returns the registered agent of the request, or responds with an error if there is none.
+func (c *Coordinator) agent(w http.ResponseWriter, r *http.Request) (*agentState, bool) {
+	id := r.URL.Query().Get(agentParam)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, agent := range c.agents {
+		if agent.ID == id {
+			return agent, true
+		}
+	}
+	http.Error(w, fmt.Sprintf("unknown agent %q", id), http.StatusNotFound)
+	return nil, false
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
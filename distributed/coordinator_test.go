@@ -0,0 +1,261 @@
+package distributed
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/mock"
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+const blockNumberBody = `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`
+
+// start starts a coordinator on a random loopback port for the duration of the test.
+func start(t *testing.T, config Config) *Coordinator {
+	t.Helper()
+	config.Listen = "127.0.0.1:0"
+	if config.Goroutines == 0 {
+		config.Goroutines = config.Agents
+	}
+	if config.StartDelay == 0 {
+		config.StartDelay = 10 * time.Millisecond
+	}
+	c, err := Start(config)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// newMock serves a mock server for the duration of the test.
+func newMock(t *testing.T) *httptest.Server {
+	t.Helper()
+	server, err := mock.New(mock.Config{Seed: 1})
+	if err != nil {
+		t.Fatalf("mock.New() error = %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+// runAgent registers an agent and sends its share of the run to the URL.
+func runAgent(coordinator, token, url string) error {
+	agent, err := Register(coordinator, "", token)
+	if err != nil {
+		return err
+	}
+	job, err := agent.Job()
+	if err != nil {
+		return err
+	}
+	u, err := relay.NewRelayUtil(relay.Config{
+		URL:        url,
+		Body:       []byte(blockNumberBody),
+		Executions: job.Executions,
+		Goroutines: job.Goroutines,
+		IDOffset:   job.IDOffset,
+		Timeout:    5 * time.Second,
+		Quiet:      true,
+	})
+	if err != nil {
+		return errors.Join(err, agent.Fail(err))
+	}
+	return agent.Run(u, job)
+}
+
+// runAgents runs n agents at once and returns their errors.
+func runAgents(n int, coordinator, token, url string) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = runAgent(coordinator, token, url)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+func TestCoordinatorMergesResults(t *testing.T) {
+	httpServer := newMock(t)
+	c := start(t, Config{Agents: 3, Executions: 100, Goroutines: 6, Token: "secret"})
+
+	for i, err := range runAgents(3, c.URL(), "secret", httpServer.URL) {
+		if err != nil {
+			t.Errorf("agent %d error = %v", i+1, err)
+		}
+	}
+	result, err := c.Wait()
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	if len(result.Results) != 100 {
+		t.Fatalf("got %d results, want 100", len(result.Results))
+	}
+	// The results are sorted by ID, which the ID offsets make unique across agents
+	for i, r := range result.Results {
+		if r.ID != int32(i+1) {
+			t.Fatalf("result %d has ID %d, want %d", i, r.ID, i+1)
+		}
+		if r.Err {
+			t.Errorf("relay %d failed: %s", r.ID, r.ErrReason)
+		}
+	}
+
+	if len(result.Agents) != 3 {
+		t.Fatalf("got %d agents, want 3", len(result.Agents))
+	}
+	relays, goroutines := 0, 0
+	for _, agent := range result.Agents {
+		relays += agent.Relays
+		goroutines += agent.Goroutines
+		if agent.Executions < 33 || agent.Executions > 34 {
+			t.Errorf("agent %s has %d executions, want an even share", agent.Name, agent.Executions)
+		}
+	}
+	if relays != 100 || goroutines != 6 {
+		t.Errorf("agents sent %d relays with %d goroutines, want 100 with 6", relays, goroutines)
+	}
+}
+
+func TestCoordinatorAgentTimeout(t *testing.T) {
+	c := start(t, Config{Agents: 2, Executions: 10, AgentTimeout: 50 * time.Millisecond})
+
+	// Both agents register, but neither ever sends results
+	for i := 0; i < 2; i++ {
+		if _, err := Register(c.URL(), "", ""); err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+	}
+
+	_, err := c.Wait()
+	if err == nil || !strings.Contains(err.Error(), "sent no results") {
+		t.Errorf("Wait() error = %v, want an agent timeout", err)
+	}
+}
+
+func TestCoordinatorAgentFails(t *testing.T) {
+	httpServer := newMock(t)
+	c := start(t, Config{Agents: 2, Executions: 10})
+
+	failed := make(chan error, 1)
+	go func() {
+		agent, err := Register(c.URL(), "broken", "")
+		if err == nil {
+			_, err = agent.Job()
+		}
+		if err == nil {
+			err = agent.Fail(errors.New("file not found"))
+		}
+		failed <- err
+	}()
+	if err := runAgent(c.URL(), "", httpServer.URL); err != nil {
+		t.Errorf("agent error = %v", err)
+	}
+	if err := <-failed; err != nil {
+		t.Fatalf("Fail() error = %v", err)
+	}
+
+	result, err := c.Wait()
+	if err == nil || !strings.Contains(err.Error(), "agent broken failed: file not found") {
+		t.Errorf("Wait() error = %v, want the failure of the agent", err)
+	}
+	if result == nil || len(result.Results) != 5 {
+		t.Errorf("Wait() result = %+v, want the 5 results of the other agent", result)
+	}
+}
+
+func TestCoordinatorRequiresToken(t *testing.T) {
+	c := start(t, Config{Agents: 1, Executions: 1, Token: "secret"})
+
+	for _, path := range []string{registerPath, jobPath, resultsPath, donePath} {
+		resp, err := http.Post(c.URL()+path+"?agent=1", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%s without a token responded with HTTP %d, want 401", path, resp.StatusCode)
+		}
+	}
+	if _, err := Register(c.URL(), "", "wrong"); err == nil {
+		t.Error("Register() with the wrong token succeeded, want an error")
+	}
+	if _, err := Register(c.URL(), "", "secret"); err != nil {
+		t.Errorf("Register() error = %v", err)
+	}
+}
+
+func TestStartRefusesNonLoopbackWithoutToken(t *testing.T) {
+	tests := []struct {
+		listen  string
+		token   string
+		wantErr bool
+	}{
+		{listen: "127.0.0.1:0"},
+		{listen: "localhost:0"},
+		{listen: "0.0.0.0:0", wantErr: true},
+		{listen: ":0", wantErr: true},
+		{listen: "0.0.0.0:0", token: "secret"},
+	}
+	for _, test := range tests {
+		t.Run(test.listen, func(t *testing.T) {
+			c, err := Start(Config{Listen: test.listen, Token: test.token, Agents: 1, Executions: 1, Goroutines: 1})
+			if (err != nil) != test.wantErr {
+				t.Errorf("Start() error = %v, want error %t", err, test.wantErr)
+			}
+			if c != nil {
+				_ = c.Close()
+			}
+		})
+	}
+}
+
+func TestCoordinatorRegisterTimeout(t *testing.T) {
+	c := start(t, Config{Agents: 3, Executions: 10, RegisterTimeout: 100 * time.Millisecond})
+
+	// Only one of the three agents registers
+	if _, err := Register(c.URL(), "", ""); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	_, err := c.Wait()
+	if err == nil || !strings.Contains(err.Error(), "1 of 3 agents registered") {
+		t.Errorf("Wait() error = %v, want a register timeout naming the registered agents", err)
+	}
+}
+
+func TestStartValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "valid", config: Config{Agents: 2, Executions: 10, Goroutines: 2}},
+		{name: "no agents", config: Config{Executions: 10, Goroutines: 2}, wantErr: true},
+		{name: "fewer executions than agents", config: Config{Agents: 4, Executions: 3, Goroutines: 4}, wantErr: true},
+		{name: "fewer goroutines than agents", config: Config{Agents: 4, Executions: 10, Goroutines: 3}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.Listen = "127.0.0.1:0"
+			c, err := Start(test.config)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Start() error = %v, want error %t", err, test.wantErr)
+			}
+			if c != nil {
+				_ = c.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package distributed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// The endpoints of the coordinator. Agents identify themselves with the agent query parameter.
+const (
+	registerPath = "/register"
+	jobPath      = "/job"
+	resultsPath  = "/results"
+	donePath     = "/done"
+	agentParam   = "agent"
+)
+
+// bearerPrefix prefixes the token of the run in the Authorization header of the requests of agents.
+const bearerPrefix = "Bearer "
+
+// heartbeatInterval is how often agents send the results of their relays to the coordinator,
+// even if they have none, so that the coordinator knows they are still running.
+const heartbeatInterval = time.Second
+
+type (
+	// registration is the request of an agent registering with the coordinator.
+	registration struct {
+		Name string `json:"name"`
+	}
+
+	// registered is the response of the coordinator to a registration.
+	registered struct {
+		ID string `json:"id"`
+	}
+
+	// Job is the share of the run that the coordinator assigns to an agent.
+	Job struct {
+		// Args are the run flags of the run, which the agent builds its relay config from.
+		Args []string `json:"args"`
+		// Executions and Goroutines are the share of the relays and goroutines of the run of the agent.
+		Executions int `json:"executions"`
+		Goroutines int `json:"goroutines"`
+		// IDOffset is added to the IDs of the relays of the agent, so that they are unique across agents.
+		IDOffset int32 `json:"id_offset"`
+		// StartAt is the time at which all agents start sending relays.
+		StartAt time.Time `json:"start_at"`
+		// StartIn is the time until StartAt when the job was sent. Agents start after it rather than at
+		// StartAt, so that they start together even if their clocks are not in sync with the coordinator.
+		StartIn time.Duration `json:"start_in"`
+	}
+
+	// done is the report of an agent that has sent all of its relays, or failed to.
+	done struct {
+		ExecTime time.Duration `json:"exec_time"`
+		Error    string        `json:"error,omitempty"`
+	}
+)
+
+// post sends a JSON request to the coordinator with the token of the run, if set,
+// and decodes its JSON response into result, if set.
+func post(client *http.Client, url, token string, request, result any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := newRequest(http.MethodPost, url, token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeResponse(resp, result)
+}
+
+// newRequest creates a request to the coordinator with the token of the run, if set.
+func newRequest(method, url, token string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", bearerPrefix+token)
+	}
+	return req, nil
+}
+
+// decodeResponse decodes the JSON response of the coordinator into result, if set.
+// Responses other than 200 OK are errors, with the text of the response as the reason.
+func decodeResponse(resp *http.Response, result any) error {
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("coordinator responded with HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(reason))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
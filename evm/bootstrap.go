@@ -0,0 +1,104 @@
+package evm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// sampledBlocks is the number of recent blocks that addresses and transactions are sampled from.
+const sampledBlocks = 3
+
+type (
+	// chain is the state of the chain that requests are generated from, read once before the run.
+	chain struct {
+		head         uint64
+		addresses    []string
+		transactions []string
+	}
+
+	// block is the subset of an eth_getBlockByNumber result with full transactions that is sampled.
+	block struct {
+		Transactions []struct {
+			Hash string `json:"hash"`
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"transactions"`
+	}
+)
+
+// bootstrap reads the latest block number of the endpoint and samples the addresses
+// and transactions of its most recent blocks, sending them as the relays of the config would be.
+func bootstrap(config relay.Config) (*chain, error) {
+	var headHex string
+	if err := call(config, "eth_blockNumber", []any{}, &headHex); err != nil {
+		return nil, fmt.Errorf("failed to get the latest block number: %w", err)
+	}
+	head, err := strconv.ParseUint(strings.TrimPrefix(headHex, "0x"), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latest block number %q: %w", headHex, err)
+	}
+
+	c := &chain{head: head}
+	seen := make(map[string]bool)
+	for i := uint64(0); i < sampledBlocks && i <= head; i++ {
+		var b block
+		if err := call(config, "eth_getBlockByNumber", []any{hexNumber(head - i), true}, &b); err != nil {
+			return nil, fmt.Errorf("failed to sample block %d: %w", head-i, err)
+		}
+		for _, tx := range b.Transactions {
+			if tx.Hash != "" {
+				c.transactions = append(c.transactions, tx.Hash)
+			}
+			for _, address := range []string{tx.From, tx.To} {
+				if address != "" && !seen[address] {
+					seen[address] = true
+					c.addresses = append(c.addresses, address)
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// call sends a single JSON-RPC request with the relay config and decodes its result.
+func call(config relay.Config, method string, params []any, result any) error {
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+
+	config.Body = body
+	config.Executions = 1
+	config.Goroutines = 1
+	config.Wait = 0
+	config.Quiet = true
+	config.SuccessBodies = true
+	config.Source = nil
+	config.Recorder = nil
+	config.Tracer = nil
+	config.Capture = relay.CaptureConfig{}
+	config.TrackFreshness = false
+	u, err := relay.NewRelayUtil(config)
+	if err != nil {
+		return err
+	}
+	u.SendRelays()
+
+	results := u.CollectResults()
+	if len(results) == 0 {
+		return fmt.Errorf("no response")
+	}
+	if results[0].Err {
+		return fmt.Errorf("%s", results[0].ErrReason)
+	}
+	return json.Unmarshal([]byte(results[0].SuccessBody), result)
+}
+
+// hexNumber encodes a number as a JSON-RPC quantity.
+func hexNumber(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
@@ -0,0 +1,214 @@
+package evm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/seeded"
+)
+
+// Supported kinds of generated requests.
+const (
+	// KindBlocks gets blocks by number from the recent block window.
+	KindBlocks = "blocks"
+	// KindLogs gets the logs of bounded block ranges from the recent block window.
+	KindLogs = "logs"
+	// KindBalances gets the balances of addresses sampled from recent blocks.
+	KindBalances = "balances"
+	// KindCalls calls the configured contracts.
+	KindCalls = "calls"
+	// KindReceipts gets the receipts of transactions sampled from recent blocks.
+	KindReceipts = "receipts"
+)
+
+// Defaults of the generator config.
+const (
+	DefaultWindow    = 128
+	DefaultLogWindow = 10
+)
+
+// defaultCallData is the calldata of contracts given without one: totalSupply(), which ERC-20 tokens implement.
+const defaultCallData = "0x18160ddd"
+
+// kinds are the supported kinds of generated requests, in the order they are described.
+var kinds = []string{KindBlocks, KindLogs, KindBalances, KindCalls, KindReceipts}
+
+type (
+	// Config configures the requests generated for an EVM endpoint.
+	Config struct {
+		// Generators are the kinds of requests generated, each weighted as kind:weight, eg. logs:2.
+		// Kinds without a weight have a weight of 1.
+		Generators []string
+		// Window is the number of recent blocks that requests are generated over.
+		Window uint64
+		// LogWindow is the number of blocks of each eth_getLogs range.
+		LogWindow uint64
+		// Contracts are called by eth_call, each as address or address:calldata.
+		Contracts []string
+		// Seed makes the generated requests reproducible. 0 uses a random seed.
+		Seed int64
+	}
+
+	// Generator is a relay.Source generating realistic requests from the recent state of an EVM chain.
+	Generator struct {
+		chain      *chain
+		kinds      []string
+		cumulative []int
+		contracts  []contract
+		window     uint64
+		logWindow  uint64
+		seed       uint64
+	}
+
+	// contract is a contract called by eth_call.
+	contract struct {
+		address, data string
+	}
+)
+
+// Enabled returns true if any generator is configured.
+func (c Config) Enabled() bool {
+	return len(c.Generators) > 0
+}
+
+// New reads the recent state of the chain with the relay config, and creates
+// a generator of the configured kinds of requests over it.
+func New(relayConfig relay.Config, config Config) (*Generator, error) {
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	if config.LogWindow == 0 {
+		config.LogWindow = DefaultLogWindow
+	}
+	if config.LogWindow > config.Window {
+		return nil, fmt.Errorf("log window of %d blocks is larger than the window of %d blocks", config.LogWindow, config.Window)
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	g := &Generator{window: config.Window, logWindow: config.LogWindow, seed: uint64(config.Seed)}
+	total := 0
+	for _, generator := range config.Generators {
+		kind, weightText, hasWeight := strings.Cut(strings.TrimSpace(generator), ":")
+		if !isKind(kind) {
+			return nil, fmt.Errorf("invalid generator %q, must be one of %s", kind, strings.Join(kinds, ", "))
+		}
+		weight := 1
+		if hasWeight {
+			var err error
+			if weight, err = strconv.Atoi(weightText); err != nil || weight < 1 {
+				return nil, fmt.Errorf("invalid weight of generator %q, must be a positive integer", generator)
+			}
+		}
+		total += weight
+		g.kinds = append(g.kinds, kind)
+		g.cumulative = append(g.cumulative, total)
+	}
+	for _, c := range config.Contracts {
+		address, data, _ := strings.Cut(strings.TrimSpace(c), ":")
+		if !strings.HasPrefix(address, "0x") || len(address) != 42 {
+			return nil, fmt.Errorf("invalid contract address %q", address)
+		}
+		if data == "" {
+			data = defaultCallData
+		}
+		g.contracts = append(g.contracts, contract{address: address, data: data})
+	}
+	if g.has(KindCalls) && len(g.contracts) == 0 {
+		return nil, fmt.Errorf("the %s generator requires at least one contract", KindCalls)
+	}
+
+	var err error
+	if g.chain, err = bootstrap(relayConfig); err != nil {
+		return nil, err
+	}
+	for _, c := range g.contracts {
+		g.chain.addresses = append(g.chain.addresses, c.address)
+	}
+	if g.has(KindBalances) && len(g.chain.addresses) == 0 {
+		return nil, fmt.Errorf("the %s generator found no addresses in the %d most recent blocks", KindBalances, sampledBlocks)
+	}
+	if g.has(KindReceipts) && len(g.chain.transactions) == 0 {
+		return nil, fmt.Errorf("the %s generator found no transactions in the %d most recent blocks", KindReceipts, sampledBlocks)
+	}
+	return g, nil
+}
+
+// Request generates the request of the relay, from the seed and the ID of the relay.
+func (g *Generator) Request(id int32) relay.Request {
+	r := seeded.New(g.seed, id)
+	kind := g.kinds[sort.SearchInts(g.cumulative, r.Intn(g.cumulative[len(g.cumulative)-1])+1)]
+
+	var method string
+	var params []any
+	switch kind {
+	case KindBlocks:
+		method, params = "eth_getBlockByNumber", []any{hexNumber(g.recentBlock(r, 0)), false}
+	case KindLogs:
+		from := g.recentBlock(r, g.logWindow-1)
+		filter := map[string]any{"fromBlock": hexNumber(from), "toBlock": hexNumber(from + g.logWindow - 1)}
+		if len(g.contracts) > 0 {
+			filter["address"] = g.contracts[r.Intn(len(g.contracts))].address
+		}
+		method, params = "eth_getLogs", []any{filter}
+	case KindBalances:
+		method, params = "eth_getBalance", []any{g.chain.addresses[r.Intn(len(g.chain.addresses))], "latest"}
+	case KindCalls:
+		c := g.contracts[r.Intn(len(g.contracts))]
+		method, params = "eth_call", []any{map[string]any{"to": c.address, "data": c.data}, "latest"}
+	case KindReceipts:
+		method, params = "eth_getTransactionReceipt", []any{g.chain.transactions[r.Intn(len(g.chain.transactions))]}
+	}
+
+	body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	return relay.Request{Body: body}
+}
+
+// String describes the generated requests and the chain state they are generated from.
+func (g *Generator) String() string {
+	total := g.cumulative[len(g.cumulative)-1]
+	shares := make([]string, 0, len(g.kinds))
+	previous := 0
+	for i, kind := range g.kinds {
+		shares = append(shares, fmt.Sprintf("%s %.0f%%", kind, float64(g.cumulative[i]-previous)/float64(total)*100))
+		previous = g.cumulative[i]
+	}
+	return fmt.Sprintf("EVM generators (%s) over the %d blocks up to %d, with %d sampled addresses and %d transactions",
+		strings.Join(shares, ", "), g.window, g.chain.head, len(g.chain.addresses), len(g.chain.transactions))
+}
+
+// recentBlock picks a block of the recent window, leaving room for span more blocks up to the head.
+func (g *Generator) recentBlock(r *seeded.Rand, span uint64) uint64 {
+	window := min(g.window, g.chain.head+1)
+	if span >= window {
+		return g.chain.head + 1 - window
+	}
+	oldest := g.chain.head + 1 - window
+	return oldest + uint64(r.Intn(int(window-span)))
+}
+
+// has returns true if the kind of request is generated.
+func (g *Generator) has(kind string) bool {
+	for _, k := range g.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// isKind returns true if the kind of request is supported.
+func isKind(kind string) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
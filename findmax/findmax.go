@@ -0,0 +1,112 @@
+package findmax
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/slo"
+)
+
+type (
+	// Config configures the search for the maximum sustainable throughput.
+	// Each step sends Relay.Executions relays with more goroutines than the last,
+	// starting at Start and adding Step goroutines per step, or doubling them if Step is 0.
+	Config struct {
+		Relay      relay.Config
+		Start      int
+		Step       int
+		Limit      int
+		Thresholds []slo.Threshold
+		// OnStep is called after each step, eg. to log its results.
+		OnStep func(Step)
+	}
+
+	// Step holds the statistics and threshold results of a single step.
+	// A step in which no relay succeeded fails, whatever its threshold results.
+	Step struct {
+		Goroutines int
+		Stats      relay.Stats
+		Results    []slo.Result
+		Passed     bool
+	}
+
+	// Result holds all the steps of the search.
+	Result struct {
+		Steps []Step
+		// Knee is the first step that failed the thresholds, if any.
+		Knee *Step
+		// MaxSustainable is the passing step with the highest RPS, if any.
+		MaxSustainable *Step
+	}
+)
+
+// validate checks that the search can make progress and has something to stop at.
+func (c Config) validate() error {
+	if len(c.Thresholds) == 0 {
+		return fmt.Errorf("at least one SLO threshold is required to find the max throughput")
+	}
+	if c.Start < 1 {
+		return fmt.Errorf("the starting goroutines must be greater than 0")
+	}
+	if c.Step < 0 {
+		return fmt.Errorf("the goroutines step must be greater than or equal to 0")
+	}
+	if c.Limit < c.Start {
+		return fmt.Errorf("the goroutines limit must be greater than or equal to the starting goroutines")
+	}
+	return nil
+}
+
+// Run raises the concurrency step by step until a step fails the thresholds or the limit is reached.
+func Run(config Config) (Result, error) {
+	if err := config.validate(); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for goroutines := config.Start; goroutines <= config.Limit; goroutines = config.next(goroutines) {
+		relayConfig := config.Relay
+		relayConfig.Goroutines = goroutines
+
+		relayUtil, err := relay.NewRelayUtil(relayConfig)
+		if err != nil {
+			return result, err
+		}
+		relayUtil.SendRelays()
+
+		stats := relayUtil.Stats()
+		results := slo.Check(config.Thresholds, stats)
+		step := Step{
+			Goroutines: goroutines,
+			Stats:      stats,
+			Results:    results,
+			Passed:     stats.SuccessfulRelays > 0 && slo.Passed(results),
+		}
+		result.Steps = append(result.Steps, step)
+
+		if config.OnStep != nil {
+			config.OnStep(step)
+		}
+
+		if !step.Passed {
+			result.Knee = &result.Steps[len(result.Steps)-1]
+			break
+		}
+	}
+
+	for i, step := range result.Steps {
+		if step.Passed && (result.MaxSustainable == nil || step.Stats.RPS > result.MaxSustainable.Stats.RPS) {
+			result.MaxSustainable = &result.Steps[i]
+		}
+	}
+
+	return result, nil
+}
+
+// next returns the goroutines of the step after the given one.
+func (c Config) next(goroutines int) int {
+	if c.Step == 0 {
+		return goroutines * 2
+	}
+	return goroutines + c.Step
+}
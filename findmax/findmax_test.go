@@ -0,0 +1,122 @@
+package findmax
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/mock"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/slo"
+)
+
+// config returns a search config sending relays to a mock server with the config.
+func config(t *testing.T, mockConfig mock.Config, thresholds ...slo.Threshold) Config {
+	t.Helper()
+	mockConfig.Seed = 1
+	server, err := mock.New(mockConfig)
+	if err != nil {
+		t.Fatalf("mock.New() error = %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	return Config{
+		Relay: relay.Config{
+			URL:        httpServer.URL,
+			Body:       []byte(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`),
+			Executions: 20,
+			Timeout:    5 * time.Second,
+			Quiet:      true,
+		},
+		Start:      1,
+		Limit:      4,
+		Thresholds: thresholds,
+	}
+}
+
+func TestRunReachesTheLimit(t *testing.T) {
+	var logged int
+	c := config(t, mock.Config{}, slo.MaxFailureRate(1))
+	c.OnStep = func(Step) { logged++ }
+
+	result, err := Run(c)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Doubling from 1 up to the limit of 4
+	if len(result.Steps) != 3 || logged != 3 {
+		t.Fatalf("got %d steps and %d logged, want 3", len(result.Steps), logged)
+	}
+	for i, want := range []int{1, 2, 4} {
+		if step := result.Steps[i]; step.Goroutines != want || !step.Passed {
+			t.Errorf("step %d = %d goroutines passed %t, want %d goroutines passed", i+1, step.Goroutines, step.Passed, want)
+		}
+	}
+	if result.Knee != nil {
+		t.Errorf("knee = %+v, want none", result.Knee)
+	}
+	if result.MaxSustainable == nil {
+		t.Error("max sustainable = nil, want the fastest step")
+	}
+}
+
+func TestRunStopsAtTheKnee(t *testing.T) {
+	c := config(t, mock.Config{ErrorRate: 100}, slo.MaxFailureRate(1))
+	c.Step = 1
+
+	result, err := Run(c)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(result.Steps) != 1 || result.Knee != &result.Steps[0] {
+		t.Fatalf("got %d steps and knee %+v, want the knee at the first step", len(result.Steps), result.Knee)
+	}
+	if result.MaxSustainable != nil {
+		t.Errorf("max sustainable = %+v, want none", result.MaxSustainable)
+	}
+}
+
+func TestRunFailsStepsWithoutSuccessfulRelays(t *testing.T) {
+	// Connection resets have no JSON-RPC error code, so the threshold alone passes
+	c := config(t, mock.Config{ResetRate: 100}, slo.MaxErrorCode(-32000, 0))
+
+	result, err := Run(c)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !slo.Passed(result.Steps[0].Results) {
+		t.Fatalf("threshold results = %+v, want them to pass", result.Steps[0].Results)
+	}
+	if result.Knee == nil || result.Knee.Goroutines != 1 {
+		t.Errorf("knee = %+v, want the first step, in which every relay failed", result.Knee)
+	}
+	if result.MaxSustainable != nil {
+		t.Errorf("max sustainable = %+v, want none", result.MaxSustainable)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	thresholds := []slo.Threshold{slo.MaxFailureRate(1)}
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "valid", config: Config{Start: 1, Limit: 10, Thresholds: thresholds}},
+		{name: "no thresholds", config: Config{Start: 1, Limit: 10}, wantErr: true},
+		{name: "no starting goroutines", config: Config{Limit: 10, Thresholds: thresholds}, wantErr: true},
+		{name: "negative step", config: Config{Start: 1, Step: -1, Limit: 10, Thresholds: thresholds}, wantErr: true},
+		{name: "limit below start", config: Config{Start: 10, Limit: 5, Thresholds: thresholds}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.config.validate(); (err != nil) != test.wantErr {
+				t.Errorf("validate() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
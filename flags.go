@@ -0,0 +1,480 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/auth"
+	"github.com/commoddity/relay-util/v2/chaos"
+	"github.com/commoddity/relay-util/v2/config"
+	"github.com/commoddity/relay-util/v2/corpus"
+	"github.com/commoddity/relay-util/v2/evm"
+	"github.com/commoddity/relay-util/v2/preset"
+	"github.com/commoddity/relay-util/v2/record"
+	"github.com/commoddity/relay-util/v2/redact"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/slo"
+	"github.com/commoddity/relay-util/v2/tracing"
+	"github.com/spf13/pflag"
+)
+
+// runFlags holds the command line flags of a run.
+type runFlags struct {
+	help bool
+
+	url, data                             string
+	executions, goroutines, wait, timeout int
+	successBodies, adaptive               bool
+	headers                               []string
+
+	maxAttempts, retryBackoff, retryMaxBackoff int
+	retryOn                                    []string
+
+	transport relay.TransportConfig
+	slo       slo.Spec
+
+	findMax                   bool
+	findMaxStep, findMaxLimit int
+
+	configFile    string
+	scenarioNames []string
+	parallel      bool
+
+	otlpEndpoint, traceFile string
+
+	savePath, htmlPath, recordPath string
+	recordUnredacted               bool
+
+	chaosLatency, chaosJitter                        int
+	chaosDropRate, chaosTruncateRate, chaosResetRate string
+	chaosSeed                                        int64
+
+	auth                       auth.Config
+	authRefresh, authJWTExpiry int
+
+	apiKeysFile, apiKeyHeader, appIDsFile, credentialOrder string
+
+	redact redact.Config
+
+	importPath, importMode string
+	importSpeed            float64
+	importSeed             int64
+	sanitize               bool
+
+	evm evm.Config
+
+	freshness      bool
+	staleThreshold uint64
+
+	preset string
+
+	capture        relay.CaptureConfig
+	captureHeaders []string
+}
+
+// register defines the run flags on the flag set.
+func (f *runFlags) register(flags *pflag.FlagSet) {
+	// Override the default help flag
+	flags.BoolVarP(&f.help, "help", "h", false, "Display help information")
+
+	// Required flags
+	flags.StringVarP(&f.url, "url", "u", "", "[REQUIRED] The URL to send the requests to. May be set in the config file instead.")
+
+	// Config file flags
+	flags.StringVarP(&f.configFile, "file", "f", "", "[OPTIONAL] A YAML or TOML config file describing named scenarios. Flags that are set override the scenarios' values.")
+	flags.StringSliceVar(&f.scenarioNames, "scenario", nil, "[OPTIONAL] The name of a scenario to run from the config file. Can be used multiple times. If more than one scenario is selected, or none is and the file has more than one, they are run as a suite.")
+	flags.BoolVar(&f.parallel, "parallel", false, "[OPTIONAL] A flag that, when set, runs the scenarios of a suite all at once instead of one after another.")
+
+	// Optional flags
+	flags.StringVarP(&f.data, "data", "d", "", "[OPTIONAL] The request body that will be sent as the relay. Must be a valid JSON string.")
+	flags.StringSliceVarP(&f.headers, "headers", "H", nil, "[OPTIONAL] Custom headers to include in the relay request, specified as -H \"Header-Name: value\". Can be used multiple times.")
+	flags.IntVarP(&f.executions, "executions", "x", 1, "[OPTIONAL] The total number of relays to execute. This defines how many times the relay will be sent.")
+	flags.BoolVarP(&f.successBodies, "success-bodies", "b", false, "[OPTIONAL] A flag that, when set, will cause the bodies of successful relay responses to be displayed in the log output.")
+	flags.IntVarP(&f.goroutines, "goroutines", "g", 5, "[OPTIONAL] The level of concurrency for sending relays. This defines how many goroutines will be used to send relays in parallel.")
+	flags.IntVarP(&f.wait, "wait", "w", 10, "[OPTIONAL] The delay between individual relay requests, measured in milliseconds. This helps to control the rate at which relays are sent.")
+	flags.IntVarP(&f.timeout, "timeout", "t", 20, "[OPTIONAL] The timeout for individual relay requests, measured in seconds.")
+	flags.IntVar(&f.maxAttempts, "max-attempts", 1, "[OPTIONAL] The maximum number of attempts per relay, including the first one. Set above 1 to retry failed relays.")
+	flags.IntVar(&f.retryBackoff, "retry-backoff", 100, "[OPTIONAL] The initial delay before retrying a relay, measured in milliseconds. It doubles on each retry, with jitter.")
+	flags.IntVar(&f.retryMaxBackoff, "retry-max-backoff", 2000, "[OPTIONAL] The maximum delay before retrying a relay, measured in milliseconds.")
+	flags.StringSliceVar(&f.retryOn, "retry-on", relay.DefaultRetryOn, "[OPTIONAL] The failures to retry: timeout, 5xx, an HTTP status code (eg. 429) or a JSON-RPC code (eg. -32000 or rpc:429). Can be used multiple times.")
+	flags.BoolVar(&f.adaptive, "adaptive", false, "[OPTIONAL] A flag that, when set, pauses sending when the endpoint throttles relays, for as long as its Retry-After or rate limit headers ask.")
+	flags.StringVar(&f.slo.MaxFailureRate, "max-failure-rate", "", "[OPTIONAL] The SLO threshold for the failure rate of relays, eg. 1%. The program exits with code 2 if it is exceeded.")
+	flags.StringVar(&f.slo.MaxP99, "max-p99", "", "[OPTIONAL] The SLO threshold for the p99 latency of successful relays, eg. 500ms. The program exits with code 2 if it is exceeded.")
+	flags.StringVar(&f.slo.MinRPS, "min-rps", "", "[OPTIONAL] The SLO threshold for the minimum RPS of successful relays, eg. 200. The program exits with code 2 if it is not reached.")
+	flags.StringSliceVar(&f.slo.MaxErrorCodes, "max-error-code", nil, "[OPTIONAL] The SLO threshold for the number of relays failing with a JSON-RPC error code, specified as <code>=<count>, eg. -32000=0. Can be used multiple times.")
+	flags.BoolVar(&f.findMax, "find-max", false, "[OPTIONAL] A flag that, when set, raises the goroutines step by step, sending --executions relays per step, until a step fails the SLO thresholds.")
+	flags.IntVar(&f.findMaxStep, "find-max-step", 0, "[OPTIONAL] The goroutines added at each --find-max step, starting from --goroutines. 0 doubles the goroutines at each step.")
+	flags.IntVar(&f.findMaxLimit, "find-max-limit", 1000, "[OPTIONAL] The maximum goroutines to try in --find-max mode.")
+	flags.IntVar(&f.transport.MaxConnsPerHost, "max-conns-per-host", 0, "[OPTIONAL] The maximum number of connections per host, including active and idle ones. 0 means unlimited.")
+	flags.IntVar(&f.transport.MaxIdleConns, "max-idle-conns", 0, "[OPTIONAL] The size of the idle connection pool per host. Defaults to the number of goroutines.")
+	flags.BoolVar(&f.transport.DisableKeepAlive, "disable-keep-alive", false, "[OPTIONAL] A flag that, when set, opens a new connection for every relay to simulate fresh clients.")
+	flags.StringVar(&f.transport.HTTPVersion, "http-version", "", "[OPTIONAL] Force the HTTP version used to send relays: 1.1, 2 or h2c (HTTP/2 without TLS). Defaults to negotiating HTTP/2 over TLS.")
+	flags.StringVar(&f.transport.ProxyURL, "proxy", "", "[OPTIONAL] An HTTP, HTTPS or SOCKS5 proxy URL to send relays through, eg. socks5://localhost:1080.")
+	flags.StringVar(&f.transport.CACertFile, "ca-cert", "", "[OPTIONAL] A PEM file of CA certificates to trust in addition to the system ones.")
+	flags.StringVar(&f.transport.ClientCertFile, "cert", "", "[OPTIONAL] A PEM client certificate file for mTLS. Requires --key.")
+	flags.StringVar(&f.transport.ClientKeyFile, "key", "", "[OPTIONAL] A PEM client private key file for mTLS. Requires --cert.")
+	flags.BoolVarP(&f.transport.InsecureSkipVerify, "insecure", "k", false, "[OPTIONAL] A flag that, when set, skips TLS certificate verification.")
+	flags.StringVar(&f.auth.Type, "auth", "", "[OPTIONAL] The auth provider computing the credentials of each relay: bearer, jwt, hmac or basic.")
+	flags.StringVar(&f.auth.TokenFile, "auth-token-file", "", "[OPTIONAL] A file to read the bearer token, HMAC secret or basic auth password from. It is read again every --auth-refresh.")
+	flags.StringVar(&f.auth.TokenEnv, "auth-token-env", "", "[OPTIONAL] An environment variable to read the bearer token, HMAC secret or basic auth password from.")
+	flags.IntVar(&f.authRefresh, "auth-refresh", 60, "[OPTIONAL] The interval at which the token file or environment variable is read again, measured in seconds.")
+	flags.StringVar(&f.auth.JWTKeyFile, "auth-jwt-key", "", "[OPTIONAL] The key to sign JWTs with: a PEM RSA or ECDSA private key for RS256 or ES256, or a raw secret for HS256.")
+	flags.IntVar(&f.authJWTExpiry, "auth-jwt-expiry", 300, "[OPTIONAL] The expiry of the minted JWTs, measured in seconds. A new JWT is minted before the current one expires.")
+	flags.StringVar(&f.auth.JWTClaims, "auth-jwt-claims", "", "[OPTIONAL] Extra claims of the minted JWTs as a JSON object, eg. '{\"sub\":\"relay-util\"}'.")
+	flags.StringVar(&f.auth.HMACHeader, "auth-hmac-header", auth.DefaultHMACHeader, "[OPTIONAL] The header carrying the HMAC signature of the request body. The timestamp is sent in <header>-Timestamp.")
+	flags.StringVar(&f.apiKeysFile, "api-keys", "", "[OPTIONAL] A file of API keys, one per line, rotated across relays in --api-key-header. Results are broken down per key.")
+	flags.StringVar(&f.apiKeyHeader, "api-key-header", "Authorization", "[OPTIONAL] The header carrying the API keys of --api-keys.")
+	flags.StringVar(&f.appIDsFile, "app-ids", "", "[OPTIONAL] A file of app IDs, one per line, rotated across relays in the URL path. The URL may contain {app_id} to place them, otherwise they are appended to its path.")
+	flags.StringVar(&f.credentialOrder, "credential-order", relay.CredentialOrderRoundRobin, "[OPTIONAL] The order in which --api-keys or --app-ids are rotated: round-robin or random.")
+	flags.StringVar(&f.auth.Basic, "auth-basic", "", "[OPTIONAL] The basic auth credentials as user:password. If the password is omitted, it is read from --auth-token-file or --auth-token-env.")
+	flags.IntVar(&f.chaosLatency, "chaos-latency", 0, "[OPTIONAL] Latency added to every relay by a local chaos proxy, measured in milliseconds.")
+	flags.IntVar(&f.chaosJitter, "chaos-jitter", 0, "[OPTIONAL] The variation of the --chaos-latency either way, measured in milliseconds.")
+	flags.StringVar(&f.chaosDropRate, "chaos-drop-rate", "", "[OPTIONAL] The percentage of relays the chaos proxy holds without a response until they time out, eg. 2%.")
+	flags.StringVar(&f.chaosTruncateRate, "chaos-truncate-rate", "", "[OPTIONAL] The percentage of responses whose body the chaos proxy cuts in half, eg. 2%.")
+	flags.StringVar(&f.chaosResetRate, "chaos-reset-rate", "", "[OPTIONAL] The percentage of relays whose connection the chaos proxy resets, eg. 2%.")
+	flags.Int64Var(&f.chaosSeed, "chaos-seed", 0, "[OPTIONAL] The seed of the random faults of the chaos proxy, for reproducible runs. 0 uses a random seed.")
+	flags.StringSliceVar(&f.redact.Headers, "redact-header", nil, "[OPTIONAL] A header whose value is masked in all output, in addition to Authorization, cookies and common API key headers. Can be used multiple times.")
+	flags.StringSliceVar(&f.redact.QueryParams, "redact-query", nil, "[OPTIONAL] A URL query parameter whose value is masked in all output, in addition to common key and token parameters. Can be used multiple times.")
+	flags.StringSliceVar(&f.redact.JSONPaths, "redact-json", nil, "[OPTIONAL] A dotted path into JSON bodies whose value is masked in all output, eg. params.0.privateKey. A * segment matches any key or index. Can be used multiple times.")
+	flags.StringArrayVar(&f.redact.Patterns, "redact-pattern", nil, "[OPTIONAL] A regular expression matching secrets that are masked in all output. If it has groups, only the groups are masked. Can be used multiple times.")
+	flags.StringVar(&f.otlpEndpoint, "otlp-endpoint", "", "[OPTIONAL] The OTLP/HTTP collector URL to export relay spans to, eg. http://localhost:4318.")
+	flags.StringVar(&f.savePath, "save", "", "[OPTIONAL] A JSON file to save the report of the run to, for use with the compare command. In a suite, each scenario is saved to its own file, eg. report.<scenario>.json.")
+	flags.StringVar(&f.htmlPath, "html", "", "[OPTIONAL] A self-contained HTML file to write the report of the run to, for sharing. In a suite, each scenario is written to its own file, eg. report.<scenario>.html.")
+	flags.StringVar(&f.importPath, "import", "", "[OPTIONAL] A HAR file, recording or JSON access log to import the JSON-RPC requests and service IDs of, to send instead of -d.")
+	flags.StringVar(&f.importMode, "import-mode", corpus.ModeCorpus, "[OPTIONAL] How imported requests are sent: corpus sends -x of them at random, weighted by how often they were imported; stream sends them all in order, with their original timing.")
+	flags.Float64Var(&f.importSpeed, "import-speed", 1, "[OPTIONAL] How many times faster than logged the imported requests are streamed. 0 streams them as fast as possible.")
+	flags.Int64Var(&f.importSeed, "import-seed", 0, "[OPTIONAL] The seed that a corpus picks the request of each relay with, for reproducible runs. 0 uses a random seed.")
+	flags.BoolVar(&f.sanitize, "sanitize", false, "[OPTIONAL] A flag that, when set, strips the credentials of imported requests before they are sent: sensitive headers and browser headers such as Cookie, Origin and Referer are removed, and secrets in bodies are masked.")
+	flags.StringSliceVar(&f.evm.Generators, "generate", nil, "[OPTIONAL] A kind of EVM request to generate from the recent state of the chain instead of -d: blocks, logs, balances, calls or receipts, optionally weighted as kind:weight, eg. logs:3. Can be used multiple times.")
+	flags.Uint64Var(&f.evm.Window, "generate-window", evm.DefaultWindow, "[OPTIONAL] The number of recent blocks that generated requests read from.")
+	flags.Uint64Var(&f.evm.LogWindow, "generate-log-window", evm.DefaultLogWindow, "[OPTIONAL] The number of blocks of each generated eth_getLogs range.")
+	flags.StringSliceVar(&f.evm.Contracts, "generate-contract", nil, "[OPTIONAL] A contract that generated eth_call and eth_getLogs requests target, as address or address:calldata. The calldata defaults to totalSupply(). Can be used multiple times.")
+	flags.Int64Var(&f.evm.Seed, "generate-seed", 0, "[OPTIONAL] The seed that the request of each relay is generated with, for reproducible runs. 0 uses a random seed.")
+	flags.StringVar(&f.preset, "preset", "", "[OPTIONAL] A chain preset to send instead of -d, with its request shape and result validation: a single method, eg. solana:getSlot or cosmos:status, or a whole chain family, eg. near, to cycle through its method library. See the presets command.")
+	flags.BoolVar(&f.freshness, "freshness", false, "[OPTIONAL] A flag that, when set, tracks how many blocks behind the highest block seen each response is, for eth_blockNumber-style requests, and reports the lag distribution.")
+	flags.Uint64Var(&f.staleThreshold, "stale-threshold", relay.DefaultStaleThreshold, "[OPTIONAL] The number of blocks behind the highest block seen past which a response is stale, counted as a soft failure.")
+	flags.StringSliceVar(&f.captureHeaders, "capture-header", nil, "[OPTIONAL] A response header, such as X-Supplier, whose value each relay is grouped by, to break down the success rate and latency by supplier, node or region. Can be used multiple times.")
+	flags.IntVar(&f.capture.Slowest, "capture-slowest", 0, "[OPTIONAL] The number of slowest successful relays to capture with their full request, response and phase timings, for the --save and --html reports.")
+	flags.IntVar(&f.capture.Failed, "capture-failed", 0, "[OPTIONAL] The number of failed relays to capture with their full request, response and phase timings, sampled at random across the run.")
+	flags.StringVar(&f.recordPath, "record", "", "[OPTIONAL] A file to record the request and response of each relay to, with its timing, for use with the replay command. Files ending in .har are written as HAR, any other file as NDJSON.")
+	flags.BoolVar(&f.recordUnredacted, "record-unredacted", false, "[OPTIONAL] Record request bodies as they were sent, without redacting them, so that they are replayed exactly. Any secrets in request bodies are written to the recording.")
+	flags.StringVar(&f.traceFile, "trace-file", "", "[OPTIONAL] A file to write relay spans to as JSON, for offline use.")
+}
+
+// relayConfig validates the flags and builds the relay config from them.
+func (f *runFlags) relayConfig() (relay.Config, error) {
+	// Convert headers from []string to http.Header
+	headerMap, err := parseHeaders(f.headers)
+	if err != nil {
+		return relay.Config{}, err
+	}
+
+	if f.url == "" {
+		return relay.Config{}, fmt.Errorf("missing required flag: -u, --url for URL")
+	}
+	if f.executions <= 0 {
+		return relay.Config{}, fmt.Errorf("executions must be greater than 0")
+	}
+	if f.capture.Slowest < 0 || f.capture.Failed < 0 {
+		return relay.Config{}, fmt.Errorf("the number of captured relays must be greater than or equal to 0")
+	}
+
+	var authProvider auth.Provider
+	if f.auth.Enabled() {
+		authConfig := f.auth
+		authConfig.Refresh = time.Duration(f.authRefresh) * time.Second
+		authConfig.JWTExpiry = time.Duration(f.authJWTExpiry) * time.Second
+		if authProvider, err = auth.New(authConfig); err != nil {
+			return relay.Config{}, fmt.Errorf("invalid auth configuration: %w", err)
+		}
+	}
+
+	var credentials []relay.Credential
+	switch {
+	case f.apiKeysFile != "" && f.appIDsFile != "":
+		return relay.Config{}, fmt.Errorf("only one of --api-keys and --app-ids may be set")
+	case f.apiKeysFile != "":
+		if f.apiKeyHeader == "" {
+			return relay.Config{}, fmt.Errorf("--api-key-header must not be empty")
+		}
+		credentials, err = relay.LoadAPIKeys(f.apiKeysFile, f.apiKeyHeader)
+	case f.appIDsFile != "":
+		credentials, err = relay.LoadAppIDs(f.appIDsFile)
+	}
+	if err != nil {
+		return relay.Config{}, err
+	}
+
+	body := []byte(f.data)
+	executions := f.executions
+	var source relay.Source
+	var decoder relay.ResultDecoder
+	if f.preset != "" {
+		if f.data != "" || f.importPath != "" || f.evm.Enabled() {
+			return relay.Config{}, fmt.Errorf("only one of -d, --import, --generate and --preset may be set")
+		}
+		p, err := preset.Parse(f.preset)
+		if err != nil {
+			return relay.Config{}, err
+		}
+		if p.Single() {
+			body = p.Body()
+		} else {
+			source = p
+		}
+		decoder = p
+	}
+	if f.importPath != "" {
+		if f.data != "" {
+			return relay.Config{}, fmt.Errorf("only one of -d and --import may be set")
+		}
+		entries, err := corpus.Load(f.importPath)
+		if err != nil {
+			return relay.Config{}, err
+		}
+		if f.sanitize {
+			entries = corpus.Sanitize(entries)
+		}
+		switch f.importMode {
+		case corpus.ModeCorpus:
+			source = corpus.New(entries, f.importSeed)
+		case corpus.ModeStream:
+			if f.importSpeed < 0 {
+				return relay.Config{}, fmt.Errorf("import speed must be greater than or equal to 0")
+			}
+			if err := corpus.CheckTimestamps(entries); err != nil {
+				return relay.Config{}, fmt.Errorf("--import-mode=%s: %w", corpus.ModeStream, err)
+			}
+			source = record.NewSource(entries, f.importSpeed)
+			executions = len(entries)
+		default:
+			return relay.Config{}, fmt.Errorf("invalid import mode %q, must be %s or %s", f.importMode, corpus.ModeCorpus, corpus.ModeStream)
+		}
+	}
+
+	config := relay.Config{
+		URL:           f.url,
+		Body:          body,
+		Headers:       headerMap,
+		Executions:    executions,
+		Goroutines:    f.goroutines,
+		Wait:          time.Duration(f.wait) * time.Millisecond,
+		Timeout:       time.Duration(f.timeout) * time.Second,
+		SuccessBodies: f.successBodies,
+		Transport:     f.transport,
+		Retry: relay.RetryConfig{
+			MaxAttempts: f.maxAttempts,
+			Backoff:     time.Duration(f.retryBackoff) * time.Millisecond,
+			MaxBackoff:  time.Duration(f.retryMaxBackoff) * time.Millisecond,
+			RetryOn:     f.retryOn,
+		},
+		Adaptive:        f.adaptive,
+		Auth:            authProvider,
+		Credentials:     credentials,
+		CredentialOrder: f.credentialOrder,
+		Source:          source,
+		TrackFreshness:  f.freshness,
+		StaleThreshold:  f.staleThreshold,
+		Decoder:         decoder,
+		Capture:         f.capture,
+		CaptureHeaders:  parseCaptureHeaders(f.captureHeaders),
+	}
+
+	// Generators read the chain with the config of the run, so that they are sent with the same headers and credentials
+	if f.evm.Enabled() {
+		if f.data != "" || f.importPath != "" {
+			return relay.Config{}, fmt.Errorf("only one of -d, --import and --generate may be set")
+		}
+		if config.Source, err = evm.New(config, f.evm); err != nil {
+			return relay.Config{}, fmt.Errorf("failed to start EVM generators: %w", err)
+		}
+	}
+	return config, nil
+}
+
+// validateFindMax returns an error for flags that cannot be used with --find-max, before anything is started.
+// Each step sends relays with the same IDs, so the faults of the chaos proxy cannot be attributed to a step.
+func (f *runFlags) validateFindMax() error {
+	if !f.findMax {
+		return nil
+	}
+	thresholds, err := f.slo.Thresholds()
+	if err != nil {
+		return fmt.Errorf("invalid SLO threshold: %w", err)
+	}
+	if len(thresholds) == 0 {
+		return fmt.Errorf("at least one SLO threshold is required to find the max throughput")
+	}
+	if f.savePath != "" || f.htmlPath != "" || f.recordPath != "" {
+		return fmt.Errorf("--save, --html and --record cannot be used with --find-max")
+	}
+	chaosConfig, err := f.chaosConfig()
+	if err != nil {
+		return err
+	}
+	if chaosConfig.Enabled() {
+		return fmt.Errorf("chaos flags cannot be used with --find-max")
+	}
+	return nil
+}
+
+// chaosConfig validates the chaos flags and builds the chaos proxy config from them.
+// The target and client of the proxy are set when it is started.
+func (f *runFlags) chaosConfig() (chaos.Config, error) {
+	config := chaos.Config{
+		Latency: time.Duration(f.chaosLatency) * time.Millisecond,
+		Jitter:  time.Duration(f.chaosJitter) * time.Millisecond,
+		Seed:    f.chaosSeed,
+	}
+	for name, rate := range map[string]struct {
+		value  string
+		target *float64
+	}{
+		"chaos drop rate":     {f.chaosDropRate, &config.DropRate},
+		"chaos truncate rate": {f.chaosTruncateRate, &config.TruncateRate},
+		"chaos reset rate":    {f.chaosResetRate, &config.ResetRate},
+	} {
+		if rate.value == "" {
+			continue
+		}
+		percent, err := slo.ParsePercent(rate.value)
+		if err != nil {
+			return chaos.Config{}, fmt.Errorf("%s: %w", name, err)
+		}
+		*rate.target = percent
+	}
+	return config, nil
+}
+
+// tracingConfig returns the tracing config from the flags.
+func (f *runFlags) tracingConfig() tracing.Config {
+	return tracing.Config{
+		OTLPEndpoint: f.otlpEndpoint,
+		FilePath:     f.traceFile,
+	}
+}
+
+// recordConfig returns the recording config from the flags.
+func (f *runFlags) recordConfig() record.Config {
+	return record.Config{UnredactedRequestBodies: f.recordUnredacted}
+}
+
+// applyScenario sets the flags that were not set on the command line from the scenario's values.
+// Headers set with -H override the scenario's headers of the same name.
+func (f *runFlags) applyScenario(scenario *config.Scenario, flags *pflag.FlagSet) error {
+	body, err := scenario.Body()
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{
+		"url":              scenario.URL,
+		"data":             string(body),
+		"max-failure-rate": scenario.Thresholds.MaxFailureRate,
+		"max-p99":          scenario.Thresholds.MaxP99,
+		"min-rps":          scenario.Thresholds.MinRPS,
+	}
+	if scenario.Executions != 0 {
+		values["executions"] = strconv.Itoa(scenario.Executions)
+	}
+	if scenario.Goroutines != 0 {
+		values["goroutines"] = strconv.Itoa(scenario.Goroutines)
+	}
+	if scenario.Wait != nil {
+		values["wait"] = strconv.Itoa(*scenario.Wait)
+	}
+	if scenario.Timeout != 0 {
+		values["timeout"] = strconv.Itoa(scenario.Timeout)
+	}
+	if scenario.SuccessBodies {
+		values["success-bodies"] = "true"
+	}
+	if len(scenario.Thresholds.MaxErrorCodes) > 0 {
+		values["max-error-code"] = strings.Join(scenario.Thresholds.MaxErrorCodes, ",")
+	}
+
+	for name, value := range values {
+		if value == "" || flags.Changed(name) {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("scenario %q: invalid %s: %w", scenario.Name, name, err)
+		}
+	}
+
+	headerMap, err := parseHeaders(f.headers)
+	if err != nil {
+		return err
+	}
+	for key, value := range scenario.Headers {
+		if _, ok := headerMap[http.CanonicalHeaderKey(key)]; !ok {
+			f.headers = append(f.headers, key+": "+value)
+		}
+	}
+
+	return nil
+}
+
+// parseHeaders converts headers specified as "Header-Name: value" to an http.Header.
+func parseHeaders(headers []string) (http.Header, error) {
+	headerMap := make(http.Header)
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header format %q. Use -H \"Header-Name: value\"", h)
+		}
+		headerMap.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return headerMap, nil
+}
+
+// parseCaptureHeaders returns the canonical names of the captured headers, each once,
+// so that a header given twice in different cases is not broken down twice.
+func parseCaptureHeaders(headers []string) []string {
+	var names []string
+	for _, header := range headers {
+		name := http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseRunFlags parses the run flags of a single scenario, applying its config file if one is set,
+// for commands that run them elsewhere, such as the coordinate and agent commands.
+func parseRunFlags(args []string) (runFlags, error) {
+	var flags runFlags
+	flagSet := pflag.NewFlagSet("run", pflag.ContinueOnError)
+	flags.register(flagSet)
+	flagSet.Usage = func() {}
+	if err := flagSet.Parse(args); err != nil {
+		return runFlags{}, err
+	}
+	if err := redact.Configure(flags.redact); err != nil {
+		return runFlags{}, err
+	}
+	if flags.configFile == "" {
+		return flags, nil
+	}
+
+	file, err := config.Load(flags.configFile)
+	if err != nil {
+		return runFlags{}, err
+	}
+	scenarios, err := file.Select(flags.scenarioNames)
+	if err != nil {
+		return runFlags{}, err
+	}
+	if len(scenarios) > 1 {
+		return runFlags{}, fmt.Errorf("only a single scenario of the config file may be selected, with --scenario")
+	}
+	if err := flags.applyScenario(&scenarios[0], flagSet); err != nil {
+		return runFlags{}, err
+	}
+	return flags, nil
+}
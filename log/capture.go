@@ -0,0 +1,51 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/fatih/color"
+)
+
+// logCaptures logs the captured slowest and failed relays. Their full requests
+// and responses are only written to the reports, as they are too long for the console.
+func logCaptures(u *relay.Util) {
+	captures := u.Captures()
+	if captures == nil || (len(captures.Slowest) == 0 && len(captures.Failed) == 0) {
+		return
+	}
+
+	blue := color.New(color.FgBlue).SprintFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+	yellow := color.New(color.FgYellow).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🔬 CAPTURED RELAYS"))
+	if len(captures.Slowest) > 0 {
+		fmt.Println("🐢 Slowest relays:")
+		for _, captured := range captures.Slowest {
+			fmt.Printf(" #%d %s · %s · sent %s · TTFB %s\n",
+				captured.ID, yellow("%s", formatDuration(captured.Latency)), formatStatus(captured.Status),
+				captured.StartedAt.Format(time.TimeOnly+".000"), formatDuration(captured.Timings.TTFB))
+		}
+	}
+	if len(captures.Failed) > 0 {
+		fmt.Printf("🚫 Failed relays (%s sampled out of %s):\n",
+			formatWithCommas(len(captures.Failed)), formatWithCommas(captures.FailedRelays))
+		for _, captured := range captures.Failed {
+			fmt.Printf(" #%d %s · sent %s · %s\n",
+				captured.ID, formatStatus(captured.Status),
+				captured.StartedAt.Format(time.TimeOnly+".000"), red("%s", captured.Error))
+		}
+	}
+	fmt.Println("💾 Their full requests and responses are written to the --save and --html reports.")
+}
+
+// formatStatus formats the HTTP status of a captured relay, which has none if no response was received.
+func formatStatus(status int) string {
+	if status == 0 {
+		return "no response"
+	}
+	return fmt.Sprintf("HTTP %d", status)
+}
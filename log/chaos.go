@@ -0,0 +1,61 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/commoddity/relay-util/v2/chaos"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/fatih/color"
+)
+
+// maxFaultReasonsLogged is the maximum number of error reasons logged per fault.
+const maxFaultReasonsLogged = 3
+
+// faultEmojis are the emojis of the faults injected by the chaos proxy.
+var faultEmojis = map[string]string{
+	chaos.FaultNone:     "🟢",
+	chaos.FaultDrop:     "🕳️ ",
+	chaos.FaultTruncate: "✂️ ",
+	chaos.FaultReset:    "🔌",
+}
+
+// PrintChaosConfig prints the latency and faults injected by the chaos proxy to the console.
+func PrintChaosConfig(config chaos.Config) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Printf("%s 🧨 Chaos proxy: +%s latency (± %s jitter), %.2f%% drops, %.2f%% truncated bodies, %.2f%% resets, seed %d\n",
+		yellow("CHAOS"), config.Latency, config.Jitter, config.DropRate, config.TruncateRate, config.ResetRate, config.Seed)
+}
+
+// logFaults logs the outcome of the relays by the fault the chaos proxy injected into them,
+// so that error classification and retry handling can be checked for each fault.
+func logFaults(results []relay.RelayResult) {
+	summaries := chaos.Summarize(results)
+	if len(summaries) == 0 {
+		return
+	}
+
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🧨 FAULTS"))
+	for _, summary := range summaries {
+		fmt.Printf("%s %s: %s relay%s · %s failed · %s recovered\n",
+			faultEmojis[summary.Fault], summary.Fault,
+			formatWithCommas(summary.Relays), suffixBasedOnLength(summary.Relays),
+			formatWithCommas(summary.Failed), formatWithCommas(summary.Recovered),
+		)
+
+		reasons := make([]string, 0, len(summary.ErrorReasons))
+		for reason := range summary.ErrorReasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Slice(reasons, func(i, j int) bool {
+			return summary.ErrorReasons[reasons[i]] > summary.ErrorReasons[reasons[j]]
+		})
+		for _, reason := range reasons[:min(len(reasons), maxFaultReasonsLogged)] {
+			fmt.Printf("   🚫 %s × %s\n", formatWithCommas(summary.ErrorReasons[reason]), reason)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/report"
+	"github.com/fatih/color"
+)
+
+// LogComparison logs the change of each metric between a baseline and the current run,
+// highlighting regressions, and the overall result if tolerances were given.
+func LogComparison(baseline, current *report.Report, comparison report.Comparison) {
+	green := color.New(color.FgGreen).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+
+	fmt.Println(blue("⚖️  COMPARISON"))
+	fmt.Printf("📁 Baseline: %s relays to %s at %s\n", formatWithCommas(baseline.Stats.TotalRelays), baseline.Config.URL, baseline.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Printf("📁 Current:  %s relays to %s at %s\n", formatWithCommas(current.Stats.TotalRelays), current.Config.URL, current.CreatedAt.Format("2006-01-02 15:04:05"))
+
+	fmt.Printf("\n")
+	fmt.Printf("%-16s  %12s  %12s  %10s  %10s\n", "METRIC", "BASELINE", "CURRENT", "CHANGE", "LIMIT")
+	for _, delta := range comparison.Deltas {
+		limit := delta.Limit
+		if limit == "" {
+			limit = "-"
+		}
+
+		// Pad the change before coloring it, so that the color codes do not break the alignment
+		change := fmt.Sprintf("%10s", delta.Change)
+		emoji := "✅"
+		switch {
+		case delta.Failed:
+			change = red(change)
+			emoji = "❌"
+		case delta.Regression:
+			change = yellow(change)
+			emoji = "⚠️ "
+		case delta.Change != "" && delta.Baseline != delta.Current:
+			change = green(change)
+		}
+
+		fmt.Printf("%-16s  %12s  %12s  %s  %10s %s\n", delta.Name, delta.Baseline, delta.Current, change, limit, emoji)
+	}
+
+	if comparison.Checked {
+		fmt.Printf("\n")
+		if comparison.Passed() {
+			fmt.Printf("🏁 %s\n", green("No regression beyond the tolerances"))
+		} else {
+			fmt.Printf("🏁 %s\n", red("Regressions beyond the tolerances"))
+		}
+	}
+}
+
+// LogReportSaved logs the path a run report was saved to.
+func LogReportSaved(path string) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Printf("%s 💾 Report saved to %s\n", green("INFO"), path)
+}
@@ -0,0 +1,58 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/fatih/color"
+)
+
+// printCredentials prints the credentials that relays are rotated across, without revealing them.
+func printCredentials(u *relay.Util) {
+	if len(u.Credentials) == 0 {
+		return
+	}
+
+	green := color.New(color.FgGreen).SprintFunc()
+
+	order := u.CredentialOrder
+	if order == "" {
+		order = relay.CredentialOrderRoundRobin
+	}
+	kind := "app ID"
+	if u.Credentials[0].Header != "" {
+		kind = u.Credentials[0].Header + " API key"
+	}
+	fmt.Printf("%s 🔑 Credentials: %s %s%s, rotated %s\n",
+		green("AUTH"), formatWithCommas(len(u.Credentials)), kind, suffixBasedOnLength(len(u.Credentials)), order)
+}
+
+// logCredentials logs the success rate, throttling and latency of the relays sent with each credential,
+// so that per-tenant rate limits and noisy neighbours show up.
+func logCredentials(u *relay.Util) {
+	breakdown := relay.CredentialBreakdown(u.Results, u.ExecTime)
+	if len(breakdown) == 0 {
+		return
+	}
+
+	blue := color.New(color.FgBlue).SprintfFunc()
+	green := color.New(color.FgGreen).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🔑 CREDENTIALS"))
+	for _, credential := range breakdown {
+		stats := credential.Stats
+		rateColor := green
+		if stats.FailedRelays > 0 {
+			rateColor = red
+		}
+		fmt.Printf("🔑 %s: %s relay%s · %s success · %s throttled · P50 %s · P99 %s\n",
+			credential.Credential,
+			formatWithCommas(stats.TotalRelays), suffixBasedOnLength(stats.TotalRelays),
+			rateColor("%.2f%%", stats.SuccessRate),
+			formatWithCommas(credential.Throttled),
+			formatDuration(stats.P50Latency), formatDuration(stats.P99Latency),
+		)
+	}
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/distributed"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/fatih/color"
+)
+
+// PrintCoordinator prints the URL that agents connect to and the number of agents the run waits for.
+func PrintCoordinator(url string, agents int) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Printf("%s 🛰️  Coordinator listening on %s, waiting for %d agent%s\n", green("INFO"), url, agents, suffixBasedOnLength(agents))
+	fmt.Printf("%s 🛰️  Start agents with: relay-util agent --coordinator=%s\n", green("INFO"), url)
+}
+
+// LogAgentRegistered logs an agent registering with the coordinator.
+func LogAgentRegistered(agent distributed.AgentSummary, agents int) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Printf("%s 🤝 Agent %s registered (%s of %d)\n", green("INFO"), agent.Name, agent.ID, agents)
+}
+
+// LogAgentDone logs an agent reporting that it sent all of its relays, or failed to.
+func LogAgentDone(agent distributed.AgentSummary) {
+	green := color.New(color.FgGreen).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+
+	if agent.Error != "" {
+		fmt.Printf("%s 💥 Agent %s failed: %s\n", red("ERROR"), agent.Name, agent.Error)
+		return
+	}
+	fmt.Printf("%s 🏁 Agent %s sent %s relay%s in %s\n",
+		green("INFO"), agent.Name, formatWithCommas(agent.Relays), suffixBasedOnLength(agent.Relays), agent.ExecTime.Round(time.Millisecond))
+}
+
+// LogAgents logs a table of the share of the run of every agent.
+func LogAgents(agents []distributed.AgentSummary) {
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	// Size the agent column to the longest name
+	nameWidth := len("AGENT")
+	for _, agent := range agents {
+		nameWidth = max(nameWidth, len(agent.Name))
+	}
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🛰️  AGENTS"))
+	fmt.Printf("%-*s  %10s  %10s  %9s  %10s  %10s\n", nameWidth, "AGENT", "GOROUTINES", "RELAYS", "SUCCESS", "RPS", "EXEC TIME")
+	for _, agent := range agents {
+		var successRate, rps float64
+		if agent.Relays > 0 {
+			successRate = float64(agent.Relays-agent.Failed) / float64(agent.Relays) * 100
+		}
+		if agent.ExecTime > 0 {
+			rps = float64(agent.Relays) / agent.ExecTime.Seconds()
+		}
+		fmt.Printf("%-*s  %10s  %10s  %8.2f%%  %10.2f  %10s\n",
+			nameWidth, agent.Name,
+			formatWithCommas(agent.Goroutines),
+			formatWithCommas(agent.Relays),
+			successRate,
+			rps,
+			agent.ExecTime.Round(time.Millisecond),
+		)
+	}
+}
+
+// LogAgentJoined logs an agent registering with its coordinator and waiting for the other agents.
+func LogAgentJoined(coordinator string, agent *distributed.Agent) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Printf("%s 🤝 Registered with %s as agent %s, waiting for the other agents\n", green("INFO"), coordinator, agent.ID)
+}
+
+// LogAgentJob logs the share of the run assigned to an agent and when it starts.
+func LogAgentJob(job distributed.Job) {
+	blue := color.New(color.FgBlue).SprintFunc()
+
+	fmt.Printf("%s 🛰️  Job: %s relay%s with %d goroutine%s, starting in %s\n",
+		blue("CONFIG"), formatWithCommas(job.Executions), suffixBasedOnLength(job.Executions),
+		job.Goroutines, suffixBasedOnLength(job.Goroutines), time.Until(job.StartAt).Round(time.Millisecond))
+}
+
+// LogAgentSummary logs a brief summary of the relays sent by an agent. The coordinator logs the full results of the run.
+func LogAgentSummary(u *relay.Util) {
+	green := color.New(color.FgGreen).SprintfFunc()
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	stats := u.Stats()
+	fmt.Printf("\n")
+	fmt.Println(blue("🛰️  AGENT SUMMARY"))
+	fmt.Printf("📡 Relays: %s, %s successful\n", formatWithCommas(stats.TotalRelays), green("%.2f%%", stats.SuccessRate))
+	fmt.Printf("⏱️  Execution time: %s, %.2f RPS\n", stats.ExecTime.Round(time.Millisecond), stats.RPS)
+	fmt.Printf("📊 Latency: p50 %s, p99 %s\n", formatDuration(stats.P50Latency), formatDuration(stats.P99Latency))
+	fmt.Printf("🏁 Results sent to the coordinator\n")
+}
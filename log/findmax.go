@@ -0,0 +1,69 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/commoddity/relay-util/v2/findmax"
+	"github.com/commoddity/relay-util/v2/slo"
+	"github.com/fatih/color"
+)
+
+// LogFindMaxStep logs a single step of the max throughput search as soon as it completes.
+func LogFindMaxStep(step findmax.Step) {
+	emoji := "✅"
+	if !step.Passed {
+		emoji = "💥"
+	}
+	fmt.Printf("\n%s %s goroutines · %.2f RPS · %.2f%% success · p99 %s\n",
+		emoji, formatWithCommas(step.Goroutines), step.Stats.RPS, step.Stats.SuccessRate, formatDuration(step.Stats.P99Latency))
+}
+
+// LogFindMax logs the table of steps of the max throughput search and the highest sustainable RPS.
+func LogFindMax(result findmax.Result) {
+	green := color.New(color.FgGreen).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("📶 FIND MAX"))
+	fmt.Printf("%10s  %10s  %9s  %10s  %10s  %s\n", "GOROUTINES", "RPS", "SUCCESS", "P50", "P99", "RESULT")
+	for _, step := range result.Steps {
+		status := green("PASS")
+		if !step.Passed {
+			status = red("FAIL")
+		}
+		fmt.Printf("%10s  %10.2f  %8.2f%%  %10s  %10s  %s\n",
+			formatWithCommas(step.Goroutines),
+			step.Stats.RPS,
+			step.Stats.SuccessRate,
+			formatDuration(step.Stats.P50Latency),
+			formatDuration(step.Stats.P99Latency),
+			status,
+		)
+	}
+
+	fmt.Printf("\n")
+	if result.Knee != nil {
+		fmt.Printf("💥 Knee at %s goroutines: %s\n", formatWithCommas(result.Knee.Goroutines), failedThresholds(result.Knee.Results))
+	} else {
+		fmt.Println("🧗 No knee found before the goroutines limit")
+	}
+	if result.MaxSustainable != nil {
+		fmt.Printf("🏁 Highest sustainable RPS: %s at %s goroutines\n",
+			green("%.2f", result.MaxSustainable.Stats.RPS), formatWithCommas(result.MaxSustainable.Goroutines))
+	} else {
+		fmt.Printf("🏁 Highest sustainable RPS: %s\n", red("no step met the thresholds"))
+	}
+}
+
+// failedThresholds describes the thresholds that failed.
+func failedThresholds(results []slo.Result) string {
+	var failed []string
+	for _, result := range results {
+		if !result.Passed {
+			failed = append(failed, fmt.Sprintf("%s %s (limit %s)", strings.ToLower(result.Name), result.Actual, result.Limit))
+		}
+	}
+	return strings.Join(failed, ", ")
+}
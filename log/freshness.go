@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/fatih/color"
+)
+
+// maxLagBarWidth is the width of the bar of the most frequent lag in the lag distribution.
+const maxLagBarWidth = 30
+
+// logFreshness logs how far behind the highest block seen the responses were, and the stale
+// responses past the threshold, so that routing to lagging suppliers shows up.
+func logFreshness(u *relay.Util) {
+	freshness := u.Freshness()
+	if freshness == nil {
+		return
+	}
+
+	blue := color.New(color.FgBlue).SprintFunc()
+	green := color.New(color.FgGreen).SprintfFunc()
+	yellow := color.New(color.FgYellow).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🧊 FRESHNESS"))
+	if freshness.Responses == 0 {
+		fmt.Printf("🤷 No block heights found in %s successful response%s\n",
+			formatWithCommas(freshness.Undecoded), suffixBasedOnLength(freshness.Undecoded))
+		return
+	}
+
+	fmt.Printf("⛓️  Blocks seen: %d to %d\n", freshness.LowestBlock, freshness.HighestBlock)
+	staleColor := green
+	if freshness.Stale > 0 {
+		staleColor = yellow
+	}
+	fmt.Printf("🥶 Stale responses: %s (%.2f%%), more than %s behind\n",
+		staleColor("%s", formatWithCommas(freshness.Stale)), freshness.StaleRate, formatBlocks(freshness.Threshold))
+	fmt.Printf("📏 Lag: p50 %d · p90 %d · p99 %d · max %d blocks\n",
+		freshness.P50Lag, freshness.P90Lag, freshness.P99Lag, freshness.MaxLag)
+	if freshness.Undecoded > 0 {
+		fmt.Printf("🤷 Responses without a block height: %s\n", formatWithCommas(freshness.Undecoded))
+	}
+
+	mostFrequent := 0
+	for _, bucket := range freshness.Distribution {
+		mostFrequent = max(mostFrequent, bucket.Count)
+	}
+	fmt.Println("📊 Lag distribution:")
+	for _, bucket := range freshness.Distribution {
+		label := fmt.Sprintf("%d", bucket.Lag)
+		if bucket.OrMore {
+			label += "+"
+		}
+		bar := strings.Repeat("█", max(1, bucket.Count*maxLagBarWidth/mostFrequent))
+		barColor := green
+		if bucket.Lag > freshness.Threshold {
+			barColor = yellow
+		}
+		fmt.Printf(" %4s %s %s (%.2f%%)\n", label, barColor("%s", bar), formatWithCommas(bucket.Count), bucket.Share)
+	}
+
+	if len(freshness.Examples) > 0 {
+		fmt.Println("🥶 Stale relays:")
+		for _, example := range freshness.Examples {
+			detail := ""
+			if example.Credential != "" {
+				detail += " with " + example.Credential
+			}
+			for _, header := range u.CaptureHeaders {
+				if value, ok := example.Headers[http.CanonicalHeaderKey(header)]; ok {
+					detail += fmt.Sprintf(" · %s: %s", http.CanonicalHeaderKey(header), value)
+				}
+			}
+			fmt.Printf(" #%d block %d, %d behind %d%s\n", example.ID, example.Block, example.Lag, example.Highest, detail)
+		}
+		if freshness.Stale > len(freshness.Examples) {
+			fmt.Printf(" ... and %s more\n", formatWithCommas(freshness.Stale-len(freshness.Examples)))
+		}
+	}
+}
+
+// formatBlocks formats a number of blocks, eg. 1 block or 0 blocks.
+func formatBlocks(blocks uint64) string {
+	if blocks == 1 {
+		return "1 block"
+	}
+	return fmt.Sprintf("%d blocks", blocks)
+}
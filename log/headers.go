@@ -0,0 +1,42 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/fatih/color"
+)
+
+// logHeaders logs the success rate and latency of the relays by the value of each captured
+// response header, so that a slow or erroring supplier or node shows up.
+func logHeaders(u *relay.Util) {
+	breakdown := relay.HeaderBreakdown(u.Results, u.CaptureHeaders, u.ExecTime)
+	if len(breakdown) == 0 {
+		return
+	}
+
+	blue := color.New(color.FgBlue).SprintfFunc()
+	green := color.New(color.FgGreen).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🏷️  RESPONSE HEADERS"))
+	header := ""
+	for _, value := range breakdown {
+		if value.Header != header {
+			header = value.Header
+			fmt.Printf("🏷️  %s:\n", header)
+		}
+		stats := value.Stats
+		rateColor := green
+		if stats.FailedRelays > 0 {
+			rateColor = red
+		}
+		fmt.Printf(" %s: %s relay%s · %s success · P50 %s · P99 %s\n",
+			value.Value,
+			formatWithCommas(stats.TotalRelays), suffixBasedOnLength(stats.TotalRelays),
+			rateColor("%.2f%%", stats.SuccessRate),
+			formatDuration(stats.P50Latency), formatDuration(stats.P99Latency),
+		)
+	}
+}
@@ -3,43 +3,40 @@ package log
 import (
 	"encoding/hex"
 	"fmt"
-	"math"
-	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/commoddity/relay-util/v2/redact"
 	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/tracing"
 	"github.com/fatih/color"
 )
 
+// maxTracesLogged is the maximum number of trace IDs logged per category.
+const maxTracesLogged = 10
+
 // PrintConfig prints the relay configuration to the console.
 func PrintConfig(u *relay.Util) {
-	// Parse the URL
-	urlStr, err := url.Parse(u.URL)
-	if err != nil {
-		panic(err)
-	}
-
-	// Replace the password in the URL, if it exists
-	if urlStr.User != nil {
-		username := urlStr.User.Username()
-		urlStr.User = url.UserPassword(username, "***")
-	}
-
 	// Define color functions
 	green := color.New(color.FgGreen).SprintFunc()
 	blue := color.New(color.FgBlue).SprintFunc()
 	magenta := color.New(color.FgMagenta).SprintFunc()
 
 	// Print the messages with colors and emojis
-	fmt.Printf("%s 🚀 Sending %s relays to %s\n", green("INFO"), formatWithCommas(u.Executions), maskAppID(urlStr.String()))
-	if u.Body != nil {
+	fmt.Printf("%s 🚀 Sending %s relays to %s\n", green("INFO"), formatWithCommas(u.Executions), redact.URL(u.URL))
+	if source, ok := u.Source.(fmt.Stringer); ok {
+		fmt.Printf("%s 📼 Requests: %s\n", magenta("REQUEST"), source)
+	} else if u.Body != nil {
 		fmt.Printf("%s 📡 Request Method: %s\n", magenta("REQUEST"), "POST")
-		fmt.Printf("%s 📦 Request Body: %s\n", magenta("REQUEST"), string(u.Body))
+		fmt.Printf("%s 📦 Request Body: %s\n", magenta("REQUEST"), redact.Body(string(u.Body)))
 	} else {
 		fmt.Printf("%s 📦 Request Method: %s\n", magenta("REQUEST"), "GET")
 	}
+	if decoder, ok := u.Decoder.(fmt.Stringer); ok && u.Source == nil {
+		fmt.Printf("%s 🧩 Preset: %s\n", magenta("REQUEST"), decoder)
+	}
 	// Print headers
 	if len(u.Headers) > 0 {
 		fmt.Printf("%s ⚙️ Headers:\n", magenta("HEADERS"))
@@ -47,11 +44,11 @@ func PrintConfig(u *relay.Util) {
 			for _, value := range values {
 				emoji := "📎"
 				color := magenta
-				if strings.ToLower(key) == "authorization" {
+				if redact.IsSensitiveHeader(key) {
 					color = green
 					emoji = "🔐"
-					value = "*****"
 				}
+				value = redact.Header(key, value)
 				if strings.ToLower(key) == "target-service-id" {
 					color = blue
 					emoji = "⛓️"
@@ -60,17 +57,80 @@ func PrintConfig(u *relay.Util) {
 			}
 		}
 	}
+	if auth, ok := u.Auth.(fmt.Stringer); ok {
+		fmt.Printf("%s 🔐 Auth: %s\n", green("AUTH"), auth)
+	}
+	printCredentials(u)
 	fmt.Printf("%s 🧵 Goroutines: %s\n", blue("CONFIG"), formatWithCommas(u.Goroutines))
 	fmt.Printf("%s ⏱️  Wait: %s\n", blue("CONFIG"), u.Wait)
 	fmt.Printf("%s ⏳ Timeout: %s\n", blue("CONFIG"), u.Timeout)
+	printTransportConfig(u.Transport, u.Goroutines)
+	if u.Adaptive {
+		fmt.Printf("%s 🚦 Adaptive rate: backing off when throttled\n", blue("CONFIG"))
+	}
+	if u.Retry.Enabled() {
+		retryOn := u.Retry.RetryOn
+		if len(retryOn) == 0 {
+			retryOn = relay.DefaultRetryOn
+		}
+		fmt.Printf("%s 🔁 Retries: up to %d attempts, backoff %s (max %s), on %s\n",
+			blue("CONFIG"), u.Retry.MaxAttempts, u.Retry.Backoff, max(u.Retry.MaxBackoff, u.Retry.Backoff), strings.Join(retryOn, ", "))
+	}
+	if len(u.CaptureHeaders) > 0 {
+		fmt.Printf("%s 🏷️  Captured headers: %s\n", blue("CONFIG"), strings.Join(u.CaptureHeaders, ", "))
+	}
+	if u.TrackFreshness {
+		fmt.Printf("%s 🧊 Freshness: responses more than %s behind are stale\n", blue("CONFIG"), formatBlocks(u.StaleThreshold))
+	}
+}
+
+// printTransportConfig prints the HTTP transport settings to the console.
+func printTransportConfig(t relay.TransportConfig, goroutines int) {
+	blue := color.New(color.FgBlue).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	httpVersion := "auto"
+	if t.HTTPVersion != relay.HTTPVersionAuto {
+		httpVersion = t.HTTPVersion
+	}
+	fmt.Printf("%s 🌐 HTTP version: %s\n", blue("CONFIG"), httpVersion)
+
+	// Connection pool settings only apply to the HTTP/1.1 transport
+	if t.HTTPVersion == relay.HTTPVersionAuto || t.HTTPVersion == relay.HTTPVersion1 {
+		maxConns := "unlimited"
+		if t.MaxConnsPerHost > 0 {
+			maxConns = formatWithCommas(t.MaxConnsPerHost)
+		}
+		idleConns := t.MaxIdleConns
+		if idleConns == 0 {
+			idleConns = goroutines
+		}
+		fmt.Printf("%s 🔗 Max connections per host: %s\n", blue("CONFIG"), maxConns)
+		fmt.Printf("%s 💤 Idle connection pool: %s\n", blue("CONFIG"), formatWithCommas(idleConns))
+		fmt.Printf("%s ♻️  Keep-alive: %t\n", blue("CONFIG"), !t.DisableKeepAlive)
+	}
+
+	if t.ProxyURL != "" {
+		fmt.Printf("%s 🛰️  Proxy: %s\n", blue("CONFIG"), redact.URL(t.ProxyURL))
+	}
+	if t.CACertFile != "" {
+		fmt.Printf("%s 📜 CA bundle: %s\n", blue("CONFIG"), t.CACertFile)
+	}
+	if t.ClientCertFile != "" {
+		fmt.Printf("%s 🪪 Client certificate: %s\n", blue("CONFIG"), t.ClientCertFile)
+	}
+	if t.InsecureSkipVerify {
+		fmt.Printf("%s ⚠️  TLS verification: %s\n", blue("CONFIG"), yellow("disabled"))
+	}
 }
 
 // LogResults logs the results of the relay execution to the console
 // from the ResultChan, which is populated by the SendRelays function.
 func LogResults(u *relay.Util) {
-	totalRelays := 0
-	successfulRelays := 0
-	failedRelays := 0
+	stats := u.Stats()
+	totalRelays := stats.TotalRelays
+	successfulRelays := stats.SuccessfulRelays
+	failedRelays := stats.FailedRelays
 	successBodies := make(map[string]int)
 	errorReasons := make(map[string]int)
 
@@ -88,25 +148,55 @@ func LogResults(u *relay.Util) {
 		formattedExecutionTime = fmt.Sprintf("%dms", u.ExecTime.Milliseconds())
 	}
 
-	// Collect latencies for successful relays
-	var latencies []int32
+	// Collect throttling statistics
+	throttledRelays := 0
+	throttledAttempts := 0
+
+	// Collect retry statistics
+	totalAttempts := 0
+	firstAttemptSuccesses := 0
+	succeededAfterRetry := 0
+	var firstAttemptLatencies, endToEndLatencies []time.Duration
+
+	// Collect the HTTP phase timings of successful relays
+	phaseDurations := make(map[string][]time.Duration)
 
-	for result := range u.ResultChan {
-		totalRelays++
+	// Collect traced relays to report their trace IDs
+	var tracedSuccesses, tracedFailures []relay.RelayResult
+
+	for _, result := range u.Results {
+		totalAttempts += result.Attempts
+		throttledAttempts += result.ThrottledAttempts
+		if result.Throttled {
+			throttledRelays++
+		}
 		if result.Err {
-			failedRelays++
 			errorReasons[result.ErrReason]++
+			if result.TraceID != "" {
+				tracedFailures = append(tracedFailures, result)
+			}
 		} else {
-			successfulRelays++
 			successBodies[result.SuccessBody]++
-			if result.Latency != 0 {
-				latencies = append(latencies, result.Latency)
+			if result.Attempts > 1 {
+				succeededAfterRetry++
+			} else {
+				firstAttemptSuccesses++
+			}
+			firstAttemptLatencies = append(firstAttemptLatencies, time.Duration(result.FirstAttemptLatency)*time.Millisecond)
+			endToEndLatencies = append(endToEndLatencies, time.Duration(result.Latency)*time.Millisecond)
+			for _, phase := range phases {
+				if d := phase.duration(result.Timings); d > 0 {
+					phaseDurations[phase.name] = append(phaseDurations[phase.name], d)
+				}
+			}
+			if result.TraceID != "" {
+				tracedSuccesses = append(tracedSuccesses, result)
 			}
 		}
 	}
 
-	successRate := float64(successfulRelays) / float64(totalRelays) * 100
-	failureRate := float64(failedRelays) / float64(totalRelays) * 100
+	successRate := stats.SuccessRate
+	failureRate := stats.FailureRate
 
 	// Determine color based on failure rate
 	var failureColorFunc func(format string, a ...interface{}) string
@@ -144,33 +234,6 @@ func LogResults(u *relay.Util) {
 		}
 	}
 
-	// Calculate average, highest, lowest, and p90 latency
-	var totalLatency int64
-	highestLatency := int32(math.MinInt32)
-	lowestLatency := int32(math.MaxInt32)
-	for _, latency := range latencies {
-		totalLatency += int64(latency)
-		if latency > highestLatency {
-			highestLatency = latency
-		}
-		if latency < lowestLatency {
-			lowestLatency = latency
-		}
-	}
-	averageLatency := float64(totalLatency) / float64(len(latencies))
-
-	// Sort latencies to find p90
-	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
-	var p90Latency int32
-	if len(latencies) > 0 {
-		p90Index := int(float64(len(latencies)) * 0.9)
-		if p90Index == 0 {
-			p90Latency = latencies[0] // If there's only one latency, it's also the p90
-		} else {
-			p90Latency = latencies[p90Index-1]
-		}
-	}
-
 	fmt.Printf("\n")
 	fmt.Println(blue("📊 RESULTS"))
 	fmt.Printf("⏳ Total time taken: %s\n", formattedExecutionTime)
@@ -209,7 +272,7 @@ func LogResults(u *relay.Util) {
 			})
 
 			for _, kv := range ss {
-				str := fmt.Sprintf("✅ %d occurrence%s - %s", kv.Value, suffixBasedOnLength(kv.Value), kv.Key)
+				str := fmt.Sprintf("✅ %d occurrence%s - %s", kv.Value, suffixBasedOnLength(kv.Value), redact.Body(kv.Key))
 
 				if decodedHex, ok := hexToTextOrNumber(kv.Key); ok {
 					str = fmt.Sprintf("%s (%s)", str, decodedHex)
@@ -232,67 +295,169 @@ func LogResults(u *relay.Util) {
 	fmt.Printf("\n")
 	fmt.Println(blue("🕒 LATENCIES"))
 	fmt.Printf("📈 RPS: %.2f\n", u.RequestsPerSecond)
-	fmt.Printf("🔊 P90 latency: %s\n", colorForLatency(int32(p90Latency))("%dms", p90Latency))
-	fmt.Printf("🐕 Average latency: %s\n", colorForLatency(int32(averageLatency))("%.2fms", averageLatency))
-	fmt.Printf("🦅 Lowest latency: %s\n", colorForLatency(int32(lowestLatency))("%dms", lowestLatency))
-	fmt.Printf("🐢 Highest latency: %s\n", colorForLatency(int32(highestLatency))("%dms", highestLatency))
-}
+	fmt.Printf("🔊 P90 latency: %s\n", colorForLatency(int32(stats.P90Latency.Milliseconds()))("%dms", stats.P90Latency.Milliseconds()))
+	fmt.Printf("🐕 Average latency: %s\n", colorForLatency(int32(stats.AverageLatency.Milliseconds()))("%s", formatDuration(stats.AverageLatency)))
+	fmt.Printf("🦅 Lowest latency: %s\n", colorForLatency(int32(stats.LowestLatency.Milliseconds()))("%dms", stats.LowestLatency.Milliseconds()))
+	fmt.Printf("🐢 Highest latency: %s\n", colorForLatency(int32(stats.HighestLatency.Milliseconds()))("%dms", stats.HighestLatency.Milliseconds()))
 
-// formatWithCommas formats a number with commas
-func formatWithCommas(number int) string {
-	in := strconv.Itoa(number)
-	out := make([]rune, 0, len(in)+(len(in)-1)/3)
+	// Log throttling statistics
+	if throttledAttempts > 0 || u.Adaptive {
+		throttledRate := float64(throttledRelays) / float64(totalRelays) * 100
+		sustainedRPS := float64(successfulRelays) / u.ExecTime.Seconds()
 
-	for i, c := range in {
-		if i > 0 && (len(in)-i)%3 == 0 {
-			out = append(out, ',')
+		fmt.Printf("\n")
+		fmt.Println(blue("🚦 THROTTLING"))
+		fmt.Printf("🛑 Throttled relays: %s (%.2f%%)\n", failureColorFunc("%s", formatWithCommas(throttledRelays)), throttledRate)
+		fmt.Printf("❌ Other failed relays: %s\n", formatWithCommas(failedRelays-throttledRelays))
+		fmt.Printf("🔂 Throttled attempts: %s\n", formatWithCommas(throttledAttempts))
+		if u.Adaptive {
+			fmt.Printf("⏸️  Time paused by throttling: %s\n", u.ThrottlePause.Round(time.Millisecond))
 		}
-		out = append(out, c)
+		fmt.Printf("📈 Sustained throughput: %.2f successful relays/s\n", sustainedRPS)
 	}
 
-	return string(out)
-}
+	// Log retry statistics
+	if u.Retry.Enabled() {
+		sort.Slice(firstAttemptLatencies, func(i, j int) bool { return firstAttemptLatencies[i] < firstAttemptLatencies[j] })
+		sort.Slice(endToEndLatencies, func(i, j int) bool { return endToEndLatencies[i] < endToEndLatencies[j] })
+		firstAttemptSuccessRate := float64(firstAttemptSuccesses) / float64(totalRelays) * 100
 
-// maskAppID masks the App ID in the URL
-func maskAppID(urlString string) string {
-	// Parse the URL
-	u, err := url.Parse(urlString)
-	if err != nil {
-		return urlString // If there's an error parsing, return the original string
+		fmt.Printf("\n")
+		fmt.Println(blue("🔁 RETRIES"))
+		fmt.Printf("🔢 Total attempts: %s (%.2f per relay)\n", formatWithCommas(totalAttempts), float64(totalAttempts)/float64(totalRelays))
+		fmt.Printf("🎯 First-attempt success rate: %.2f%%\n", firstAttemptSuccessRate)
+		fmt.Printf("♻️  Succeeded after retry: %s\n", formatWithCommas(succeededAfterRetry))
+		fmt.Printf("🔊 P90 latency: first attempt %s · end-to-end %s\n",
+			formatDuration(relay.Percentile(firstAttemptLatencies, 0.9)),
+			formatDuration(relay.Percentile(endToEndLatencies, 0.9)),
+		)
+		fmt.Printf("🐕 Average latency: first attempt %s · end-to-end %s\n",
+			formatDuration(averageDuration(firstAttemptLatencies)),
+			formatDuration(averageDuration(endToEndLatencies)),
+		)
 	}
 
-	maskedURL := u.Scheme + "://"
+	// Log the outcome of the faults injected by the chaos proxy
+	logFaults(u.Results)
 
-	// Mask the password if it exists
-	if u.User != nil {
-		username := u.User.Username()
-		_, hasPassword := u.User.Password()
-		if hasPassword {
-			// Build userInfo with masked password
-			maskedURL += username + ":******@"
-		} else {
-			// Include username only if there's no password
-			maskedURL += username + "@"
+	// Log the outcome of the relays sent with each rotated credential
+	logCredentials(u)
+
+	// Log the outcome of the relays by the value of each captured response header
+	logHeaders(u)
+
+	// Log how far behind the chain head the responses were
+	logFreshness(u)
+
+	// Log the relays captured with their full request and response
+	logCaptures(u)
+
+	// Log HTTP phase percentiles
+	if len(phaseDurations) > 0 {
+		fmt.Printf("\n")
+		fmt.Println(blue("⏱️  PHASES"))
+		for _, phase := range phases {
+			durations := phaseDurations[phase.name]
+			if len(durations) == 0 {
+				fmt.Printf("%s %s: not observed\n", phase.emoji, phase.name)
+				continue
+			}
+			sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+			fmt.Printf("%s %s: p50 %s · p90 %s · p99 %s (%s relay%s)\n",
+				phase.emoji, phase.name,
+				formatDuration(relay.Percentile(durations, 0.5)),
+				formatDuration(relay.Percentile(durations, 0.9)),
+				formatDuration(relay.Percentile(durations, 0.99)),
+				formatWithCommas(len(durations)), suffixBasedOnLength(len(durations)),
+			)
 		}
 	}
 
-	// Add host
-	maskedURL += u.Host
+	// Log trace IDs of the slowest and failed relays
+	if len(tracedSuccesses) > 0 || len(tracedFailures) > 0 {
+		fmt.Printf("\n")
+		fmt.Println(blue("🔭 TRACES"))
+
+		if len(tracedSuccesses) > 0 {
+			sort.Slice(tracedSuccesses, func(i, j int) bool { return tracedSuccesses[i].Latency > tracedSuccesses[j].Latency })
+			fmt.Println("🐢 Slowest relays:")
+			for _, result := range tracedSuccesses[:min(len(tracedSuccesses), maxTracesLogged)] {
+				fmt.Printf(" #%d %s - trace %s\n", result.ID, colorForLatency(result.Latency)("%dms", result.Latency), result.TraceID)
+			}
+		}
 
-	// Mask the AppID if it's present in the path
-	parts := strings.Split(u.Path, "/")
-	if len(parts) > 0 {
-		lastPartIndex := len(parts) - 1
-		if len(parts[lastPartIndex]) == 8 {
-			parts[lastPartIndex] = "******"
+		if len(tracedFailures) > 0 {
+			sort.Slice(tracedFailures, func(i, j int) bool { return tracedFailures[i].ID < tracedFailures[j].ID })
+			fmt.Println("🚫 Failed relays:")
+			for _, result := range tracedFailures[:min(len(tracedFailures), maxTracesLogged)] {
+				fmt.Printf(" #%d %s - trace %s\n", result.ID, red("%s", result.ErrReason), result.TraceID)
+			}
+			if len(tracedFailures) > maxTracesLogged {
+				fmt.Printf(" ... and %s more\n", formatWithCommas(len(tracedFailures)-maxTracesLogged))
+			}
 		}
-		u.Path = strings.Join(parts, "/")
+	}
+}
+
+// PrintTracingConfig prints the tracing exporter configuration to the console.
+func PrintTracingConfig(config tracing.Config) {
+	if !config.Enabled() {
+		return
 	}
 
-	// Add path
-	maskedURL += u.Path
+	blue := color.New(color.FgBlue).SprintFunc()
 
-	return maskedURL
+	if config.OTLPEndpoint != "" {
+		fmt.Printf("%s 🔭 Tracing: OTLP %s\n", blue("CONFIG"), config.OTLPEndpoint)
+	}
+	if config.FilePath != "" {
+		fmt.Printf("%s 🔭 Tracing: file %s\n", blue("CONFIG"), config.FilePath)
+	}
+}
+
+// phases lists the HTTP request phases logged in the results, in request order.
+var phases = []struct {
+	name     string
+	emoji    string
+	duration func(relay.PhaseTimings) time.Duration
+}{
+	{"DNS lookup", "🔎", func(t relay.PhaseTimings) time.Duration { return t.DNS }},
+	{"TCP connect", "🔌", func(t relay.PhaseTimings) time.Duration { return t.Connect }},
+	{"TLS handshake", "🔐", func(t relay.PhaseTimings) time.Duration { return t.TLS }},
+	{"Time to first byte", "⏳", func(t relay.PhaseTimings) time.Duration { return t.TTFB }},
+	{"Body download", "📥", func(t relay.PhaseTimings) time.Duration { return t.Body }},
+}
+
+// averageDuration returns the mean of the durations.
+func averageDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
+
+// formatDuration formats a duration in milliseconds with two decimals.
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+// formatWithCommas formats a number with commas
+func formatWithCommas(number int) string {
+	in := strconv.Itoa(number)
+	out := make([]rune, 0, len(in)+(len(in)-1)/3)
+
+	for i, c := range in {
+		if i > 0 && (len(in)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, c)
+	}
+
+	return string(out)
 }
 
 // suffixBasedOnLength returns a suffix based on the length of the count
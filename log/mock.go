@@ -0,0 +1,55 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/commoddity/relay-util/v2/mock"
+	"github.com/fatih/color"
+)
+
+// PrintMockConfig prints the address and behavior of the mock server to the console.
+func PrintMockConfig(addr string, config mock.Config) {
+	green := color.New(color.FgGreen).SprintFunc()
+	blue := color.New(color.FgBlue).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Printf("%s 🧪 Mock JSON-RPC server listening on http://%s\n", green("INFO"), addr)
+	fmt.Printf("%s ⏱️  Latency: %s\n", blue("CONFIG"), config.Latency)
+	fmt.Printf("%s ⛓️  Block: %s, advancing every %s\n", blue("CONFIG"), formatWithCommas(int(config.StartBlock)), config.BlockTime)
+	if config.ErrorRate > 0 {
+		codes := make([]string, 0, len(config.ErrorCodes))
+		for _, code := range config.ErrorCodes {
+			codes = append(codes, strconv.Itoa(code))
+		}
+		fmt.Printf("%s 💥 JSON-RPC errors: %.2f%% of calls, codes %s\n", yellow("FAULT"), config.ErrorRate, strings.Join(codes, ", "))
+	}
+	if config.HTTP5xxRate > 0 {
+		fmt.Printf("%s 🔥 HTTP 503: %.2f%% of requests\n", yellow("FAULT"), config.HTTP5xxRate)
+	}
+	if config.HTTP429Rate > 0 {
+		fmt.Printf("%s 🚦 HTTP 429: %.2f%% of requests, Retry-After %s\n", yellow("FAULT"), config.HTTP429Rate, config.RetryAfter)
+	}
+	if config.SlowBodyRate > 0 {
+		fmt.Printf("%s 🐢 Slow bodies: %.2f%% of responses, streamed over %s\n", yellow("FAULT"), config.SlowBodyRate, config.SlowBodyDelay)
+	}
+	if config.ResetRate > 0 {
+		fmt.Printf("%s 🔌 Connection resets: %.2f%% of requests\n", yellow("FAULT"), config.ResetRate)
+	}
+	fmt.Printf("%s 🎲 Seed: %d\n", blue("CONFIG"), config.Seed)
+}
+
+// LogMockStats logs the requests served by the mock server and the faults it injected.
+func LogMockStats(stats mock.Stats) {
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🧪 MOCK SERVER"))
+	fmt.Printf("📥 Requests: %s (%s JSON-RPC calls)\n", formatWithCommas(int(stats.Requests)), formatWithCommas(int(stats.Calls)))
+	fmt.Printf("💥 JSON-RPC errors: %s\n", formatWithCommas(int(stats.Errors)))
+	fmt.Printf("🔥 HTTP 503: %s\n", formatWithCommas(int(stats.HTTP5xx)))
+	fmt.Printf("🚦 HTTP 429: %s\n", formatWithCommas(int(stats.HTTP429)))
+	fmt.Printf("🐢 Slow bodies: %s\n", formatWithCommas(int(stats.SlowBodies)))
+	fmt.Printf("🔌 Connection resets: %s\n", formatWithCommas(int(stats.Resets)))
+}
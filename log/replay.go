@@ -0,0 +1,50 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/record"
+	"github.com/fatih/color"
+)
+
+// LogRecordingSaved logs where the recorded relays of a run were saved.
+func LogRecordingSaved(path string, relays int) {
+	green := color.New(color.FgGreen).SprintFunc()
+
+	fmt.Printf("%s 📼 %s relay%s recorded to %s\n", green("INFO"), formatWithCommas(relays), suffixBasedOnLength(relays), path)
+}
+
+// LogRedactedSkipped warns that recorded relays were skipped, as redaction changed their request bodies.
+func LogRedactedSkipped(relays int) {
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	fmt.Printf("%s ⚠️  %s recorded relay%s skipped, as redaction changed the request body. Record with --record-unredacted to replay every relay.\n",
+		yellow("WARN"), formatWithCommas(relays), suffixBasedOnLength(relays))
+}
+
+// LogReplay logs how the responses of the replayed relays compare with the recorded ones.
+func LogReplay(comparison record.Comparison) {
+	blue := color.New(color.FgBlue).SprintfFunc()
+	green := color.New(color.FgGreen).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+
+	rateColor := green
+	if comparison.Mismatched > 0 {
+		rateColor = red
+	}
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🔁 REPLAY"))
+	fmt.Printf("✅ Matched responses: %s of %s (%s)\n",
+		formatWithCommas(comparison.Matched), formatWithCommas(comparison.Relays), rateColor("%.2f%%", comparison.MatchRate()))
+	fmt.Printf("🔀 Mismatched responses: %s\n", formatWithCommas(comparison.Mismatched))
+	fmt.Printf("🆕 Newly failed relays: %s\n", formatWithCommas(comparison.NewlyFailed))
+	fmt.Printf("🩹 Newly succeeded relays: %s\n", formatWithCommas(comparison.NewlySucceeded))
+	for _, mismatch := range comparison.Examples {
+		method := mismatch.Method
+		if method == "" {
+			method = "request"
+		}
+		fmt.Printf(" #%d %s: %s\n", mismatch.ID, method, red("%s", mismatch.Reason))
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/slo"
+	"github.com/fatih/color"
+)
+
+// LogThresholds logs the pass/fail result of each SLO threshold and the overall result.
+func LogThresholds(results []slo.Result) {
+	green := color.New(color.FgGreen).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🎯 THRESHOLDS"))
+	for _, result := range results {
+		if result.Passed {
+			fmt.Printf("✅ %s: %s (limit %s) %s\n", result.Name, result.Actual, result.Limit, green("PASS"))
+		} else {
+			fmt.Printf("❌ %s: %s (limit %s) %s\n", result.Name, result.Actual, result.Limit, red("FAIL"))
+		}
+	}
+
+	if slo.Passed(results) {
+		fmt.Printf("🏁 %s\n", green("All thresholds passed"))
+	} else {
+		fmt.Printf("🏁 %s\n", red("Thresholds failed"))
+	}
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"fmt"
+
+	"github.com/commoddity/relay-util/v2/suite"
+	"github.com/fatih/color"
+)
+
+// PrintScenarioHeader prints the name of a scenario of a suite before its config or results.
+func PrintScenarioHeader(name string) {
+	magenta := color.New(color.FgMagenta).SprintFunc()
+
+	fmt.Printf("\n")
+	fmt.Printf("%s 🎬 Scenario: %s\n", magenta("SCENARIO"), name)
+}
+
+// LogSuiteSummary logs a summary table of every scenario of a suite and the overall result.
+func LogSuiteSummary(results []suite.Result) {
+	green := color.New(color.FgGreen).SprintfFunc()
+	red := color.New(color.FgRed).SprintfFunc()
+	blue := color.New(color.FgBlue).SprintfFunc()
+
+	// Size the scenario column to the longest name
+	nameWidth := len("SCENARIO")
+	for _, result := range results {
+		nameWidth = max(nameWidth, len(result.Name))
+	}
+
+	fmt.Printf("\n")
+	fmt.Println(blue("🧾 SUITE SUMMARY"))
+	fmt.Printf("%-*s  %10s  %9s  %10s  %10s  %10s  %s\n", nameWidth, "SCENARIO", "RELAYS", "SUCCESS", "RPS", "P50", "P99", "THRESHOLDS")
+	for _, result := range results {
+		thresholds := "-"
+		switch {
+		case len(result.Thresholds) == 0:
+		case result.Passed():
+			thresholds = green("PASS")
+		default:
+			thresholds = red("FAIL")
+		}
+		fmt.Printf("%-*s  %10s  %8.2f%%  %10.2f  %10s  %10s  %s\n",
+			nameWidth, result.Name,
+			formatWithCommas(result.Stats.TotalRelays),
+			result.Stats.SuccessRate,
+			result.Stats.RPS,
+			formatDuration(result.Stats.P50Latency),
+			formatDuration(result.Stats.P99Latency),
+			thresholds,
+		)
+	}
+
+	fmt.Printf("\n")
+	if suite.Passed(results) {
+		fmt.Printf("🏁 %s\n", green("Suite passed"))
+	} else {
+		fmt.Printf("🏁 %s\n", red("Suite failed"))
+	}
+}
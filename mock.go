@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/log"
+	"github.com/commoddity/relay-util/v2/mock"
+	"github.com/commoddity/relay-util/v2/slo"
+	"github.com/spf13/pflag"
+)
+
+// mockCommand serves a mock JSON-RPC endpoint with configurable latency and faults until interrupted.
+func mockCommand(args []string) {
+	var (
+		help                                                         bool
+		addr, latency                                                string
+		errorRate, http5xxRate, http429Rate, slowBodyRate, resetRate string
+		errorCodes                                                   []int
+		retryAfter, slowBodyDelay, blockTime                         time.Duration
+		startBlock                                                   uint64
+		seed                                                         int64
+	)
+
+	flagSet := pflag.NewFlagSet("mock", pflag.ExitOnError)
+	flagSet.BoolVarP(&help, "help", "h", false, "Display help information")
+	flagSet.StringVarP(&addr, "addr", "a", "127.0.0.1:8545", "[OPTIONAL] The address to listen on.")
+	flagSet.StringVarP(&latency, "latency", "l", "0ms", "[OPTIONAL] The latency distribution of responses: <duration>, uniform:<min>:<max>, normal:<mean>:<stddev> or exponential:<mean>.")
+	flagSet.StringVar(&errorRate, "error-rate", "", "[OPTIONAL] The percentage of JSON-RPC calls answered with an error, eg. 5%. Applies to each call of a batch.")
+	flagSet.IntSliceVar(&errorCodes, "error-code", []int{-32000}, "[OPTIONAL] The JSON-RPC error codes to answer with, picked at random. Can be used multiple times.")
+	flagSet.StringVar(&http5xxRate, "5xx-rate", "", "[OPTIONAL] The percentage of requests answered with HTTP 503, eg. 1%.")
+	flagSet.StringVar(&http429Rate, "429-rate", "", "[OPTIONAL] The percentage of requests answered with HTTP 429 and a JSON-RPC rate limit error, eg. 10%.")
+	flagSet.DurationVar(&retryAfter, "retry-after", time.Second, "[OPTIONAL] The Retry-After header of HTTP 429 responses. 0 omits the header.")
+	flagSet.StringVar(&slowBodyRate, "slow-body-rate", "", "[OPTIONAL] The percentage of responses whose body is streamed slowly, eg. 5%.")
+	flagSet.DurationVar(&slowBodyDelay, "slow-body-delay", 2*time.Second, "[OPTIONAL] The time over which a slow body is streamed.")
+	flagSet.StringVar(&resetRate, "reset-rate", "", "[OPTIONAL] The percentage of requests whose connection is reset without a response, eg. 1%.")
+	flagSet.DurationVar(&blockTime, "block-time", 12*time.Second, "[OPTIONAL] The interval at which the served block number advances.")
+	flagSet.Uint64Var(&startBlock, "start-block", 1_000_000, "[OPTIONAL] The block number served when the server starts.")
+	flagSet.Int64Var(&seed, "seed", 0, "[OPTIONAL] The seed of the random latencies and faults, for reproducible runs. 0 uses a random seed.")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s mock [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Serves a mock JSON-RPC endpoint with configurable latency and faults, to test relay-util and gateways locally.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flagSet.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExample command:\n")
+		fmt.Fprintf(os.Stderr, "  %s mock --latency=normal:50ms:10ms --error-rate=2%% --error-code=-32000 --429-rate=5%% --reset-rate=1%%\n", os.Args[0])
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if help {
+		flagSet.Usage()
+		return
+	}
+
+	config := mock.Config{
+		ErrorCodes:    errorCodes,
+		RetryAfter:    retryAfter,
+		SlowBodyDelay: slowBodyDelay,
+		BlockTime:     blockTime,
+		StartBlock:    startBlock,
+		Seed:          seed,
+	}
+
+	var err error
+	if config.Latency, err = mock.ParseLatency(latency); err != nil {
+		exitWithUsageError(err)
+	}
+	for name, rate := range map[string]struct {
+		value  string
+		target *float64
+	}{
+		"error rate":     {errorRate, &config.ErrorRate},
+		"5xx rate":       {http5xxRate, &config.HTTP5xxRate},
+		"429 rate":       {http429Rate, &config.HTTP429Rate},
+		"slow body rate": {slowBodyRate, &config.SlowBodyRate},
+		"reset rate":     {resetRate, &config.ResetRate},
+	} {
+		if rate.value == "" {
+			continue
+		}
+		if *rate.target, err = slo.ParsePercent(rate.value); err != nil {
+			exitWithUsageError(fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	server, err := mock.New(config)
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Printf("🚫 Failed to listen on %s: %v\n", addr, err)
+		os.Exit(1)
+	}
+
+	httpServer := &http.Server{Handler: server}
+	log.PrintMockConfig(listener.Addr().String(), server.Config())
+
+	// Serve until interrupted, then log what was served
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("🚫 Mock server failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	log.LogMockStats(server.Stats())
+}
@@ -0,0 +1,98 @@
+package mock
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/parse"
+)
+
+// Latency distributions supported by the mock server.
+const (
+	DistributionFixed       = "fixed"
+	DistributionUniform     = "uniform"
+	DistributionNormal      = "normal"
+	DistributionExponential = "exponential"
+)
+
+// Latency is the distribution the response latency of the mock server is drawn from.
+type Latency struct {
+	Distribution string
+	// Mean is the latency of the fixed distribution and the mean of the others.
+	Mean time.Duration
+	// Min and Max bound the uniform distribution.
+	Min, Max time.Duration
+	// StdDev is the standard deviation of the normal distribution.
+	StdDev time.Duration
+}
+
+// ParseLatency parses a latency distribution such as "50ms", "fixed:50ms",
+// "uniform:10ms:100ms", "normal:50ms:10ms" or "exponential:50ms".
+// Durations given as plain numbers are taken as milliseconds.
+func ParseLatency(spec string) (Latency, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	if len(parts) == 1 {
+		parts = []string{DistributionFixed, parts[0]}
+	}
+
+	durations := make([]time.Duration, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		d, err := parse.Duration(part)
+		if err != nil {
+			return Latency{}, fmt.Errorf("invalid latency distribution %q: %w", spec, err)
+		}
+		durations = append(durations, d)
+	}
+
+	latency := Latency{Distribution: strings.ToLower(parts[0])}
+	switch {
+	case latency.Distribution == DistributionFixed && len(durations) == 1,
+		latency.Distribution == DistributionExponential && len(durations) == 1:
+		latency.Mean = durations[0]
+	case latency.Distribution == DistributionUniform && len(durations) == 2:
+		latency.Min, latency.Max = durations[0], durations[1]
+		if latency.Min > latency.Max {
+			return Latency{}, fmt.Errorf("invalid latency distribution %q: min must not be greater than max", spec)
+		}
+		latency.Mean = (latency.Min + latency.Max) / 2
+	case latency.Distribution == DistributionNormal && len(durations) == 2:
+		latency.Mean, latency.StdDev = durations[0], durations[1]
+	default:
+		return Latency{}, fmt.Errorf("invalid latency distribution %q, must be <duration>, fixed:<duration>, uniform:<min>:<max>, normal:<mean>:<stddev> or exponential:<mean>", spec)
+	}
+
+	return latency, nil
+}
+
+// String returns the latency distribution in the form accepted by ParseLatency.
+func (l Latency) String() string {
+	switch l.Distribution {
+	case DistributionUniform:
+		return fmt.Sprintf("%s:%s:%s", l.Distribution, l.Min, l.Max)
+	case DistributionNormal:
+		return fmt.Sprintf("%s:%s:%s", l.Distribution, l.Mean, l.StdDev)
+	case DistributionExponential:
+		return fmt.Sprintf("%s:%s", l.Distribution, l.Mean)
+	default:
+		return fmt.Sprintf("%s:%s", DistributionFixed, l.Mean)
+	}
+}
+
+// sample draws a latency from the distribution. Negative samples are clamped to zero.
+func (l Latency) sample(r *rand.Rand) time.Duration {
+	var d float64
+	switch l.Distribution {
+	case DistributionUniform:
+		d = float64(l.Min) + r.Float64()*float64(l.Max-l.Min)
+	case DistributionNormal:
+		d = float64(l.Mean) + r.NormFloat64()*float64(l.StdDev)
+	case DistributionExponential:
+		d = r.ExpFloat64() * float64(l.Mean)
+	default:
+		d = float64(l.Mean)
+	}
+	return time.Duration(math.Max(d, 0))
+}
@@ -0,0 +1,337 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/netfault"
+)
+
+// slowBodyChunks is the number of chunks a slow body is streamed in.
+const slowBodyChunks = 10
+
+// errorMessages are the messages of the JSON-RPC errors injected by the mock server.
+var errorMessages = map[int]string{
+	-32700: "parse error",
+	-32600: "invalid request",
+	-32601: "method not found",
+	-32602: "invalid params",
+	-32603: "internal error",
+	-32000: "server error",
+	-32005: "rate limit exceeded",
+}
+
+type (
+	// Config configures the behavior of the mock server.
+	// Rates are percentages between 0 and 100. The reset, 429 and 5xx rates apply to
+	// each HTTP request and are exclusive of each other, while the error rate applies
+	// to each JSON-RPC call, including every call of a batch.
+	Config struct {
+		Latency       Latency
+		ErrorRate     float64
+		ErrorCodes    []int
+		HTTP5xxRate   float64
+		HTTP429Rate   float64
+		RetryAfter    time.Duration
+		SlowBodyRate  float64
+		SlowBodyDelay time.Duration
+		ResetRate     float64
+		// BlockTime is the interval at which the block number served by the mock server advances.
+		BlockTime time.Duration
+		// StartBlock is the block number served when the mock server starts.
+		StartBlock uint64
+		// Seed seeds the random faults. 0 uses a random seed.
+		Seed int64
+	}
+
+	// Stats counts the requests served by the mock server and the faults it injected.
+	Stats struct {
+		Requests   int64
+		Calls      int64
+		Errors     int64
+		HTTP5xx    int64
+		HTTP429    int64
+		SlowBodies int64
+		Resets     int64
+	}
+
+	// Server is a JSON-RPC server with configurable latency and faults,
+	// used to test relay-util and gateways locally. It implements http.Handler.
+	Server struct {
+		config  Config
+		started time.Time
+
+		mu   sync.Mutex
+		rand *rand.Rand
+
+		requests, calls, errors, http5xx, http429, slowBodies, resets atomic.Int64
+	}
+
+	// request is a single JSON-RPC call.
+	request struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+	}
+
+	// response is the response to a single JSON-RPC call.
+	response struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  interface{}     `json:"result,omitempty"`
+		Error   *rpcError       `json:"error,omitempty"`
+	}
+
+	rpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// fault is the HTTP level fault injected into a request.
+	fault int
+)
+
+const (
+	faultNone fault = iota
+	faultReset
+	fault429
+	fault5xx
+)
+
+// New creates a mock server after validating its config.
+func New(config Config) (*Server, error) {
+	for name, rate := range map[string]float64{
+		"error rate":     config.ErrorRate,
+		"5xx rate":       config.HTTP5xxRate,
+		"429 rate":       config.HTTP429Rate,
+		"slow body rate": config.SlowBodyRate,
+		"reset rate":     config.ResetRate,
+	} {
+		if rate < 0 || rate > 100 {
+			return nil, fmt.Errorf("invalid %s %.2f%%, must be between 0%% and 100%%", name, rate)
+		}
+	}
+	if total := config.ResetRate + config.HTTP429Rate + config.HTTP5xxRate; total > 100 {
+		return nil, fmt.Errorf("the reset, 429 and 5xx rates add up to %.2f%%, must be at most 100%%", total)
+	}
+	if config.ErrorRate > 0 && len(config.ErrorCodes) == 0 {
+		config.ErrorCodes = []int{-32000}
+	}
+	if config.BlockTime <= 0 {
+		config.BlockTime = 12 * time.Second
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	return &Server{
+		config:  config,
+		started: time.Now(),
+		rand:    rand.New(rand.NewSource(config.Seed)),
+	}, nil
+}
+
+// Config returns the config of the server, with its defaults applied.
+func (s *Server) Config() Config {
+	return s.config
+}
+
+// Stats returns the number of requests served and faults injected so far.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Requests:   s.requests.Load(),
+		Calls:      s.calls.Load(),
+		Errors:     s.errors.Load(),
+		HTTP5xx:    s.http5xx.Load(),
+		HTTP429:    s.http429.Load(),
+		SlowBodies: s.slowBodies.Load(),
+		Resets:     s.resets.Load(),
+	}
+}
+
+// BlockNumber returns the block number currently served by the mock server.
+func (s *Server) BlockNumber() uint64 {
+	return s.config.StartBlock + uint64(time.Since(s.started)/s.config.BlockTime)
+}
+
+// ServeHTTP serves a JSON-RPC request or batch, injecting the configured latency and faults.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	body, _ := io.ReadAll(r.Body)
+
+	latency, fault, slowBody := s.roll()
+	time.Sleep(latency)
+
+	switch fault {
+	case faultReset:
+		s.resets.Add(1)
+		netfault.Reset(w)
+		return
+	case fault429:
+		s.http429.Add(1)
+		if s.config.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.config.RetryAfter.Round(time.Second).Seconds())))
+		}
+		s.write(w, http.StatusTooManyRequests, s.errorResponse(json.RawMessage("null"), -32005), false)
+		return
+	case fault5xx:
+		s.http5xx.Add(1)
+		http.Error(w, "mock: service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	if slowBody {
+		s.slowBodies.Add(1)
+	}
+
+	// GET requests, as sent by relay-util without a body, are served as a health check
+	if r.Method == http.MethodGet && len(bytes.TrimSpace(body)) == 0 {
+		s.write(w, http.StatusOK, response{JSONRPC: "2.0", ID: json.RawMessage("null"), Result: "ok"}, slowBody)
+		return
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var requests []request
+		if err := json.Unmarshal(trimmed, &requests); err != nil {
+			s.write(w, http.StatusOK, s.errorResponse(json.RawMessage("null"), -32700), slowBody)
+			return
+		}
+		if len(requests) == 0 {
+			s.write(w, http.StatusOK, s.errorResponse(json.RawMessage("null"), -32600), slowBody)
+			return
+		}
+		responses := make([]response, 0, len(requests))
+		for _, req := range requests {
+			responses = append(responses, s.call(req))
+		}
+		s.write(w, http.StatusOK, responses, slowBody)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(trimmed, &req); err != nil {
+		s.write(w, http.StatusOK, s.errorResponse(json.RawMessage("null"), -32700), slowBody)
+		return
+	}
+	s.write(w, http.StatusOK, s.call(req), slowBody)
+}
+
+// roll draws the latency, the HTTP level fault and whether the body is streamed slowly for a request.
+func (s *Server) roll() (time.Duration, fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latency := s.config.Latency.sample(s.rand)
+
+	// The HTTP level faults are exclusive, so they share a single roll
+	f := faultNone
+	roll := s.rand.Float64() * 100
+	switch {
+	case roll < s.config.ResetRate:
+		f = faultReset
+	case roll < s.config.ResetRate+s.config.HTTP429Rate:
+		f = fault429
+	case roll < s.config.ResetRate+s.config.HTTP429Rate+s.config.HTTP5xxRate:
+		f = fault5xx
+	}
+
+	slowBody := s.rand.Float64()*100 < s.config.SlowBodyRate
+	return latency, f, slowBody
+}
+
+// call serves a single JSON-RPC call, injecting an error at the configured rate.
+func (s *Server) call(req request) response {
+	s.calls.Add(1)
+
+	id := req.ID
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+
+	s.mu.Lock()
+	injectError := s.rand.Float64()*100 < s.config.ErrorRate
+	var code int
+	if injectError {
+		code = s.config.ErrorCodes[s.rand.Intn(len(s.config.ErrorCodes))]
+	}
+	s.mu.Unlock()
+
+	if injectError {
+		s.errors.Add(1)
+		return s.errorResponse(id, code)
+	}
+	return response{JSONRPC: "2.0", ID: id, Result: s.result(req)}
+}
+
+// result returns the result of a JSON-RPC method. The block number advances every BlockTime.
+// Unknown methods return "0x0", so that any method may be load tested.
+func (s *Server) result(req request) interface{} {
+	block := s.BlockNumber()
+	switch req.Method {
+	case "eth_blockNumber":
+		return fmt.Sprintf("0x%x", block)
+	case "eth_chainId":
+		return "0x1"
+	case "net_version":
+		return "1"
+	case "web3_clientVersion":
+		return "relay-util/mock"
+	case "eth_getBlockByNumber":
+		minedAt := s.started.Add(time.Duration(block-s.config.StartBlock) * s.config.BlockTime)
+		return map[string]string{
+			"number":    fmt.Sprintf("0x%x", block),
+			"hash":      fmt.Sprintf("0x%064x", block),
+			"timestamp": fmt.Sprintf("0x%x", minedAt.Unix()),
+		}
+	case "getSlot", "getBlockHeight":
+		return block
+	case "getHealth":
+		return "ok"
+	default:
+		return "0x0"
+	}
+}
+
+// errorResponse returns a JSON-RPC error response with the given code.
+func (s *Server) errorResponse(id json.RawMessage, code int) response {
+	message, ok := errorMessages[code]
+	if !ok {
+		message = "mock error"
+	}
+	return response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// write writes the JSON response. A slow body is streamed in chunks spread over SlowBodyDelay.
+func (s *Server) write(w http.ResponseWriter, status int, v interface{}, slowBody bool) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	flusher, canFlush := w.(http.Flusher)
+	if !slowBody || !canFlush {
+		w.WriteHeader(status)
+		_, _ = w.Write(body)
+		return
+	}
+
+	w.WriteHeader(status)
+	chunkSize := (len(body) + slowBodyChunks - 1) / slowBodyChunks
+	for start := 0; start < len(body); start += chunkSize {
+		_, _ = w.Write(body[start:min(start+chunkSize, len(body))])
+		flusher.Flush()
+		time.Sleep(s.config.SlowBodyDelay / slowBodyChunks)
+	}
+}
@@ -0,0 +1,92 @@
+package mock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewValidatesRates(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "no faults", config: Config{}},
+		{name: "all rates in range", config: Config{ErrorRate: 5, HTTP5xxRate: 10, HTTP429Rate: 20, SlowBodyRate: 100, ResetRate: 30}},
+		{name: "exclusive faults adding up to 100%", config: Config{HTTP5xxRate: 50, HTTP429Rate: 25, ResetRate: 25}},
+		{name: "negative error rate", config: Config{ErrorRate: -1}, wantErr: true},
+		{name: "error rate above 100%", config: Config{ErrorRate: 101}, wantErr: true},
+		{name: "5xx rate above 100%", config: Config{HTTP5xxRate: 150}, wantErr: true},
+		{name: "negative slow body rate", config: Config{SlowBodyRate: -0.5}, wantErr: true},
+		{name: "exclusive faults above 100%", config: Config{HTTP5xxRate: 50, HTTP429Rate: 30, ResetRate: 30}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(test.config)
+			if (err != nil) != test.wantErr {
+				t.Errorf("New() error = %v, want error %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAppliesDefaults(t *testing.T) {
+	server, err := New(Config{ErrorRate: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	config := server.Config()
+	if len(config.ErrorCodes) != 1 || config.ErrorCodes[0] != -32000 {
+		t.Errorf("error codes = %v, want [-32000]", config.ErrorCodes)
+	}
+	if config.BlockTime != 12*time.Second {
+		t.Errorf("block time = %s, want 12s", config.BlockTime)
+	}
+	if config.Seed == 0 {
+		t.Error("seed = 0, want a random seed")
+	}
+}
+
+func TestParseLatency(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Latency
+		wantErr bool
+	}{
+		{spec: "50ms", want: Latency{Distribution: DistributionFixed, Mean: 50 * time.Millisecond}},
+		{spec: "75", want: Latency{Distribution: DistributionFixed, Mean: 75 * time.Millisecond}},
+		{spec: "fixed:1s", want: Latency{Distribution: DistributionFixed, Mean: time.Second}},
+		{spec: "uniform:10ms:30ms", want: Latency{Distribution: DistributionUniform, Mean: 20 * time.Millisecond, Min: 10 * time.Millisecond, Max: 30 * time.Millisecond}},
+		{spec: "Normal:50ms:10ms", want: Latency{Distribution: DistributionNormal, Mean: 50 * time.Millisecond, StdDev: 10 * time.Millisecond}},
+		{spec: "exponential:20ms", want: Latency{Distribution: DistributionExponential, Mean: 20 * time.Millisecond}},
+		{spec: "uniform:30ms:10ms", wantErr: true},
+		{spec: "uniform:10ms", wantErr: true},
+		{spec: "normal:50ms", wantErr: true},
+		{spec: "pareto:50ms", wantErr: true},
+		{spec: "fast", wantErr: true},
+		{spec: "-5ms", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			got, err := ParseLatency(test.spec)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseLatency(%q) error = %v, want error %t", test.spec, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseLatency(%q) = %+v, want %+v", test.spec, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLatencyStringRoundTrips(t *testing.T) {
+	for _, spec := range []string{"fixed:50ms", "uniform:10ms:30ms", "normal:50ms:10ms", "exponential:20ms"} {
+		latency, err := ParseLatency(spec)
+		if err != nil {
+			t.Fatalf("ParseLatency(%q) error = %v", spec, err)
+		}
+		if got := latency.String(); got != spec {
+			t.Errorf("String() = %q, want %q", got, spec)
+		}
+	}
+}
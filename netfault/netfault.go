@@ -0,0 +1,24 @@
+// Package netfault injects connection level faults into HTTP responses, for the mock server and the chaos proxy.
+package netfault
+
+import (
+	"net"
+	"net/http"
+)
+
+// Reset closes the connection of the request abruptly. Over HTTP/1, the connection is
+// hijacked and closed with a TCP RST. Over HTTP/2, the stream is aborted instead.
+func Reset(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	_ = conn.Close()
+}
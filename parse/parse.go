@@ -0,0 +1,32 @@
+// Package parse parses the durations and percentages of command line flags and config files,
+// so that packages such as slo and mock accept them in the same form without depending on each other.
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration parses a duration such as "500ms" or "1.5s". A plain number is taken as milliseconds.
+func Duration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if ms, err := strconv.ParseFloat(value, 64); err == nil {
+		value = fmt.Sprintf("%gms", ms)
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, must be a duration such as 500ms", value)
+	}
+	return d, nil
+}
+
+// Percent parses a percentage such as "1%" or "0.5".
+func Percent(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid percentage %q, must be between 0%% and 100%%", value)
+	}
+	return percent, nil
+}
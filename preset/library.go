@@ -0,0 +1,80 @@
+package preset
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// blockHashPattern matches the hex block hashes of Bitcoin, which have no 0x prefix.
+var blockHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// families are the bundled method libraries, by chain family.
+// The heights of a family are all in the same unit, so that their freshness can be compared.
+var families = []Family{
+	{
+		Name:   "evm",
+		Height: "block number",
+		Methods: []Method{
+			{Name: "eth_blockNumber", Params: []any{}, decode: quantity},
+			{Name: "eth_chainId", Params: []any{}, decode: validOnly(quantity)},
+			{Name: "eth_gasPrice", Params: []any{}, decode: validOnly(quantity)},
+			{Name: "eth_getBlockByNumber", Params: []any{"latest", false}, decode: field(quantity, "number")},
+			{Name: "net_version", Params: []any{}, decode: validOnly(decimal)},
+		},
+	},
+	{
+		Name:   "solana",
+		Height: "slot",
+		Methods: []Method{
+			{Name: "getSlot", Params: []any{}, decode: number},
+			{Name: "getBlockHeight", Params: []any{}, decode: validOnly(number)},
+			{Name: "getEpochInfo", Params: []any{}, decode: field(number, "absoluteSlot")},
+			{Name: "getLatestBlockhash", Params: []any{map[string]any{"commitment": "finalized"}}, decode: field(number, "context", "slot")},
+			{Name: "getBalance", Params: []any{"11111111111111111111111111111111"}, decode: field(number, "context", "slot")},
+			{Name: "getHealth", Params: []any{}, decode: equals("ok")},
+			{Name: "getVersion", Params: []any{}, decode: validOnly(field(text, "solana-core"))},
+		},
+	},
+	{
+		Name:   "cosmos",
+		Height: "block height",
+		Methods: []Method{
+			{Name: "status", Params: map[string]any{}, decode: field(decimal, "sync_info", "latest_block_height")},
+			{Name: "block", Params: map[string]any{}, decode: field(decimal, "block", "header", "height")},
+			{Name: "abci_info", Params: map[string]any{}, decode: field(decimal, "response", "last_block_height")},
+			{Name: "health", Params: map[string]any{}, decode: object},
+			{Name: "net_info", Params: map[string]any{}, decode: validOnly(field(decimal, "n_peers"))},
+		},
+	},
+	{
+		Name:   "near",
+		Height: "block height",
+		Methods: []Method{
+			{Name: "status", Params: []any{}, decode: field(number, "sync_info", "latest_block_height")},
+			{Name: "block", Params: map[string]any{"finality": "final"}, decode: field(number, "header", "height")},
+			{Name: "gas_price", Params: []any{nil}, decode: validOnly(field(decimal, "gas_price"))},
+			{Name: "validators", Params: []any{nil}, decode: validOnly(field(array, "current_validators"))},
+		},
+	},
+	{
+		Name:    "bitcoin",
+		Height:  "block height",
+		JSONRPC: "1.0",
+		Methods: []Method{
+			{Name: "getblockcount", Params: []any{}, decode: number},
+			{Name: "getbestblockhash", Params: []any{}, decode: blockHash},
+			{Name: "getblockchaininfo", Params: []any{}, decode: field(number, "blocks")},
+			{Name: "getmempoolinfo", Params: []any{}, decode: validOnly(field(number, "size"))},
+			{Name: "getnetworkinfo", Params: []any{}, decode: validOnly(field(number, "version"))},
+		},
+	},
+}
+
+// blockHash validates a Bitcoin block hash.
+func blockHash(result any) (uint64, bool, error) {
+	hash, ok := result.(string)
+	if !ok || !blockHashPattern.MatchString(hash) {
+		return 0, false, fmt.Errorf("expected a block hash, got %s", describe(result))
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,254 @@
+package preset
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+type (
+	// Family is the method library of a chain family, such as Solana or Cosmos.
+	Family struct {
+		Name string
+		// Height is the unit of the block heights decoded from the results of the family, eg. slot.
+		Height string
+		// JSONRPC is the JSON-RPC version of the requests of the family. It defaults to 2.0.
+		JSONRPC string
+		Methods []Method
+	}
+
+	// Method is a method of a method library, with the request shape and result of the chain family.
+	Method struct {
+		Name   string
+		Params any
+		decode decodeFunc
+	}
+
+	// Preset sends a single method of a family, or cycles through its whole method library,
+	// and validates the results of the relays and decodes their block heights.
+	Preset struct {
+		family  Family
+		methods []Method
+	}
+
+	// decodeFunc validates a result and returns its block height, if it has one.
+	// Whether it has one is returned even for invalid results, so that it is known for each method.
+	decodeFunc func(result any) (uint64, bool, error)
+)
+
+// Families returns the bundled method libraries.
+func Families() []Family {
+	return families
+}
+
+// Parse returns the preset of a family, eg. solana, which cycles through its method library,
+// or of a single method of a family, eg. solana:getSlot.
+func Parse(name string) (*Preset, error) {
+	familyName, methodName, single := strings.Cut(name, ":")
+	for _, family := range families {
+		if !strings.EqualFold(family.Name, familyName) {
+			continue
+		}
+		if !single {
+			return &Preset{family: family, methods: family.Methods}, nil
+		}
+		for _, method := range family.Methods {
+			if method.Name == methodName {
+				return &Preset{family: family, methods: []Method{method}}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown %s method %q, must be one of %s", family.Name, methodName, strings.Join(family.methodNames(), ", "))
+	}
+
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.Name)
+	}
+	return nil, fmt.Errorf("unknown preset %q, must be one of %s", familyName, strings.Join(names, ", "))
+}
+
+// Single returns true if the preset sends a single method, as the body of every relay.
+func (p *Preset) Single() bool {
+	return len(p.methods) == 1
+}
+
+// Body returns the request of the first method of the preset.
+func (p *Preset) Body() []byte {
+	return p.family.body(p.methods[0], 1)
+}
+
+// Request returns the request of the relay, cycling through the methods of the preset.
+func (p *Preset) Request(id int32) relay.Request {
+	method := p.methods[int(id-1)%len(p.methods)]
+	return relay.Request{Body: p.family.body(method, id)}
+}
+
+// Decode validates the result of a request of the family and returns its block height, if it has one.
+func (p *Preset) Decode(request []byte, result string) (uint64, bool, error) {
+	var r struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(request, &r); err != nil {
+		return 0, false, nil
+	}
+	for _, method := range p.family.Methods {
+		if method.Name != r.Method {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(result)))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return 0, false, err
+		}
+		height, hasHeight, err := method.decode(value)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s: %w", method.Name, err)
+		}
+		return height, hasHeight, nil
+	}
+	return 0, false, nil
+}
+
+// String describes the requests sent by the preset.
+func (p *Preset) String() string {
+	if p.Single() {
+		return p.family.Name + ":" + p.methods[0].Name
+	}
+	return fmt.Sprintf("%s method library, cycling through %s", p.family.Name, strings.Join(p.family.methodNames(), ", "))
+}
+
+// body returns the JSON-RPC request of the method.
+func (f Family) body(method Method, id int32) []byte {
+	version := f.JSONRPC
+	if version == "" {
+		version = "2.0"
+	}
+	body, _ := json.Marshal(map[string]any{"jsonrpc": version, "id": id, "method": method.Name, "params": method.Params})
+	return body
+}
+
+// methodNames returns the names of the methods of the family.
+func (f Family) methodNames() []string {
+	names := make([]string, 0, len(f.Methods))
+	for _, method := range f.Methods {
+		names = append(names, method.Name)
+	}
+	return names
+}
+
+// HasHeight returns true if the results of the method have a block height.
+func (m Method) HasHeight() bool {
+	_, hasHeight, _ := m.decode(nil)
+	return hasHeight
+}
+
+// quantity decodes an EVM hex quantity.
+func quantity(result any) (uint64, bool, error) {
+	text, ok := result.(string)
+	if hex, isHex := strings.CutPrefix(text, "0x"); ok && isHex {
+		if height, err := strconv.ParseUint(hex, 16, 64); err == nil {
+			return height, true, nil
+		}
+	}
+	return 0, true, fmt.Errorf("expected a hex quantity, got %s", describe(result))
+}
+
+// decimal decodes a number encoded as decimal text, as Cosmos encodes heights.
+func decimal(result any) (uint64, bool, error) {
+	if text, ok := result.(string); ok {
+		if height, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return height, true, nil
+		}
+	}
+	return 0, true, fmt.Errorf("expected a decimal number, got %s", describe(result))
+}
+
+// number decodes a non-negative JSON integer.
+func number(result any) (uint64, bool, error) {
+	if n, ok := result.(json.Number); ok {
+		if height, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return height, true, nil
+		}
+	}
+	return 0, true, fmt.Errorf("expected a non-negative integer, got %s", describe(result))
+}
+
+// text validates a non-empty string.
+func text(result any) (uint64, bool, error) {
+	if s, ok := result.(string); !ok || s == "" {
+		return 0, false, fmt.Errorf("expected text, got %s", describe(result))
+	}
+	return 0, false, nil
+}
+
+// object validates a JSON object.
+func object(result any) (uint64, bool, error) {
+	if _, ok := result.(map[string]any); !ok {
+		return 0, false, fmt.Errorf("expected an object, got %s", describe(result))
+	}
+	return 0, false, nil
+}
+
+// array validates a JSON array.
+func array(result any) (uint64, bool, error) {
+	if _, ok := result.([]any); !ok {
+		return 0, false, fmt.Errorf("expected an array, got %s", describe(result))
+	}
+	return 0, false, nil
+}
+
+// equals validates that the result is the expected string.
+func equals(expected string) decodeFunc {
+	return func(result any) (uint64, bool, error) {
+		if result != expected {
+			return 0, false, fmt.Errorf("expected %q, got %s", expected, describe(result))
+		}
+		return 0, false, nil
+	}
+}
+
+// field decodes a nested field of a result object with the decoder, eg. field(number, "context", "slot").
+func field(decode decodeFunc, path ...string) decodeFunc {
+	return func(result any) (uint64, bool, error) {
+		value := result
+		for _, key := range path {
+			object, ok := value.(map[string]any)
+			if !ok {
+				_, hasHeight, _ := decode(nil)
+				return 0, hasHeight, fmt.Errorf("expected an object with %s, got %s", strings.Join(path, "."), describe(result))
+			}
+			value = object[key]
+		}
+		height, hasHeight, err := decode(value)
+		if err != nil {
+			return 0, hasHeight, fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+		}
+		return height, hasHeight, nil
+	}
+}
+
+// validOnly validates the result with the decoder, but drops its height, for results
+// whose numbers are not block heights, such as a chain ID.
+func validOnly(decode decodeFunc) decodeFunc {
+	return func(result any) (uint64, bool, error) {
+		_, _, err := decode(result)
+		return 0, false, err
+	}
+}
+
+// describe describes an unexpected result in validation errors.
+func describe(result any) string {
+	if result == nil {
+		return "nothing"
+	}
+	data, err := json.Marshal(result)
+	if err != nil || len(data) > 64 {
+		return fmt.Sprintf("a %T", result)
+	}
+	return string(data)
+}
@@ -0,0 +1,218 @@
+package preset
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// request is the JSON-RPC envelope of a preset request.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int32           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// mustParse returns the preset of the name.
+func mustParse(t *testing.T, name string) *Preset {
+	t.Helper()
+	p, err := Parse(name)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", name, err)
+	}
+	return p
+}
+
+// decodeRequest decodes a preset request.
+func decodeRequest(t *testing.T, body []byte) request {
+	t.Helper()
+	var r request
+	if err := json.Unmarshal(body, &r); err != nil {
+		t.Fatalf("request %s is not JSON: %v", body, err)
+	}
+	return r
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantSingle bool
+		wantErr    string
+	}{
+		{name: "evm"},
+		{name: "Solana"},
+		{name: "cosmos:status", wantSingle: true},
+		{name: "bitcoin:getblockcount", wantSingle: true},
+		{name: "tron", wantErr: `unknown preset "tron"`},
+		{name: "solana:eth_blockNumber", wantErr: `unknown solana method "eth_blockNumber"`},
+		{name: "near:", wantErr: `unknown near method ""`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := Parse(test.name)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %s", test.name, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", test.name, err)
+			}
+			if p.Single() != test.wantSingle {
+				t.Errorf("Single() = %t, want %t", p.Single(), test.wantSingle)
+			}
+		})
+	}
+}
+
+func TestRequestCycles(t *testing.T) {
+	p := mustParse(t, "evm")
+	methods := families[0].methodNames()
+
+	// Relays of a distributed agent start after its relay ID offset
+	for _, offset := range []int32{0, 3, 1000} {
+		for i := int32(1); i <= int32(2*len(methods)); i++ {
+			id := offset + i
+			r := decodeRequest(t, p.Request(id).Body)
+			if want := methods[int(id-1)%len(methods)]; r.Method != want {
+				t.Errorf("Request(%d) method = %s, want %s", id, r.Method, want)
+			}
+			if r.ID != id {
+				t.Errorf("Request(%d) id = %d, want the relay ID", id, r.ID)
+			}
+		}
+	}
+}
+
+func TestBitcoinEnvelope(t *testing.T) {
+	r := decodeRequest(t, mustParse(t, "bitcoin:getblockcount").Body())
+	if r.JSONRPC != "1.0" {
+		t.Errorf("jsonrpc = %q, want 1.0", r.JSONRPC)
+	}
+	if r := decodeRequest(t, mustParse(t, "evm:eth_blockNumber").Body()); r.JSONRPC != "2.0" {
+		t.Errorf("jsonrpc = %q, want the default of 2.0", r.JSONRPC)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	const hash = "00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054"
+	tests := []struct {
+		preset     string
+		result     string
+		wantHeight uint64
+		wantHas    bool // an invalid result reports no height
+		wantErr    bool
+	}{
+		// EVM
+		{preset: "evm:eth_blockNumber", result: `"0x10d4f"`, wantHeight: 68943, wantHas: true},
+		{preset: "evm:eth_blockNumber", result: `"68943"`, wantErr: true},
+		{preset: "evm:eth_chainId", result: `"0x1"`},
+		{preset: "evm:eth_chainId", result: `1`, wantErr: true},
+		{preset: "evm:eth_getBlockByNumber", result: `{"number":"0x64","hash":"0xab"}`, wantHeight: 100, wantHas: true},
+		{preset: "evm:eth_getBlockByNumber", result: `null`, wantErr: true},
+		{preset: "evm:net_version", result: `"1"`},
+		// Solana
+		{preset: "solana:getSlot", result: `250000000`, wantHeight: 250000000, wantHas: true},
+		{preset: "solana:getSlot", result: `-1`, wantErr: true},
+		{preset: "solana:getBlockHeight", result: `230000000`},
+		{preset: "solana:getLatestBlockhash", result: `{"context":{"slot":42},"value":{}}`, wantHeight: 42, wantHas: true},
+		{preset: "solana:getLatestBlockhash", result: `{"value":{}}`, wantErr: true},
+		{preset: "solana:getHealth", result: `"ok"`},
+		{preset: "solana:getHealth", result: `"behind"`, wantErr: true},
+		{preset: "solana:getVersion", result: `{"solana-core":"1.18.0"}`},
+		// Cosmos
+		{preset: "cosmos:status", result: `{"sync_info":{"latest_block_height":"123"}}`, wantHeight: 123, wantHas: true},
+		{preset: "cosmos:status", result: `{"sync_info":"syncing"}`, wantErr: true},
+		{preset: "cosmos:block", result: `{"block":{"header":{"height":"77"}}}`, wantHeight: 77, wantHas: true},
+		{preset: "cosmos:health", result: `{}`},
+		{preset: "cosmos:health", result: `"ok"`, wantErr: true},
+		{preset: "cosmos:net_info", result: `{"n_peers":"12"}`},
+		// NEAR
+		{preset: "near:status", result: `{"sync_info":{"latest_block_height":99}}`, wantHeight: 99, wantHas: true},
+		{preset: "near:block", result: `{"header":{"height":100}}`, wantHeight: 100, wantHas: true},
+		{preset: "near:validators", result: `{"current_validators":[]}`},
+		{preset: "near:validators", result: `{"current_validators":{}}`, wantErr: true},
+		// Bitcoin
+		{preset: "bitcoin:getblockcount", result: `840000`, wantHeight: 840000, wantHas: true},
+		{preset: "bitcoin:getbestblockhash", result: `"` + hash + `"`},
+		{preset: "bitcoin:getbestblockhash", result: `"0x` + hash[2:] + `"`, wantErr: true},
+		{preset: "bitcoin:getbestblockhash", result: `"` + strings.ToUpper(hash) + `"`, wantErr: true},
+		{preset: "bitcoin:getblockchaininfo", result: `{"blocks":840000}`, wantHeight: 840000, wantHas: true},
+		{preset: "bitcoin:getmempoolinfo", result: `{"size":3000}`},
+	}
+	for _, test := range tests {
+		t.Run(test.preset+" "+test.result, func(t *testing.T) {
+			p := mustParse(t, test.preset)
+			height, hasHeight, err := p.Decode(p.Body(), test.result)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Decode() error = %v, want error %t", err, test.wantErr)
+			}
+			if height != test.wantHeight || hasHeight != test.wantHas {
+				t.Errorf("Decode() = %d, %t, want %d, %t", height, hasHeight, test.wantHeight, test.wantHas)
+			}
+		})
+	}
+}
+
+func TestDecodeIgnoresOtherRequests(t *testing.T) {
+	p := mustParse(t, "solana")
+	for _, body := range []string{`not json`, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`} {
+		if height, hasHeight, err := p.Decode([]byte(body), `"garbage"`); height != 0 || hasHeight || err != nil {
+			t.Errorf("Decode(%s) = %d, %t, %v, want nothing", body, height, hasHeight, err)
+		}
+	}
+}
+
+func TestValidOnlyDropsHeight(t *testing.T) {
+	decode := validOnly(quantity)
+
+	if height, hasHeight, err := decode("0x1"); height != 0 || hasHeight || err != nil {
+		t.Errorf("validOnly(quantity)(0x1) = %d, %t, %v, want no height", height, hasHeight, err)
+	}
+	if _, _, err := decode("one"); err == nil {
+		t.Error("validOnly(quantity)(one) error = nil, want the error of the decoder")
+	}
+}
+
+func TestFieldMissingPath(t *testing.T) {
+	decode := field(number, "context", "slot")
+	tests := []struct {
+		name   string
+		result any
+	}{
+		{name: "not an object", result: "slot"},
+		{name: "missing parent", result: map[string]any{"value": 1}},
+		{name: "missing leaf", result: map[string]any{"context": map[string]any{}}},
+		{name: "parent not an object", result: map[string]any{"context": []any{}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, hasHeight, err := decode(test.result)
+			if err == nil {
+				t.Fatal("error = nil, want a missing path error")
+			}
+			if !strings.Contains(err.Error(), "context.slot") {
+				t.Errorf("error = %v, want it to name the path", err)
+			}
+			if !hasHeight {
+				t.Error("hasHeight = false, want the height of the method reported even for invalid results")
+			}
+		})
+	}
+}
+
+func TestHasHeight(t *testing.T) {
+	for _, family := range Families() {
+		heights := 0
+		for _, method := range family.Methods {
+			if method.HasHeight() {
+				heights++
+			}
+		}
+		if heights == 0 {
+			t.Errorf("%s has no method with a %s", family.Name, family.Height)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/commoddity/relay-util/v2/preset"
+	"github.com/fatih/color"
+	"github.com/spf13/pflag"
+)
+
+// presetsCommand lists the chain presets and the methods of their libraries.
+func presetsCommand(args []string) {
+	flagSet := pflag.NewFlagSet("presets", pflag.ExitOnError)
+	help := flagSet.BoolP("help", "h", false, "Display help information")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s presets\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Lists the chain presets that --preset accepts, with the methods of their libraries.\n")
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if *help {
+		flagSet.Usage()
+		return
+	}
+
+	blue := color.New(color.FgBlue).SprintFunc()
+	for _, family := range preset.Families() {
+		fmt.Printf("%s - heights are %ss\n", blue(family.Name), family.Height)
+		for _, method := range family.Methods {
+			height := ""
+			if method.HasHeight() {
+				height = " 🧊"
+			}
+			fmt.Printf("  %s:%s%s\n", family.Name, method.Name, height)
+		}
+	}
+	fmt.Printf("\n🧊 The results of these methods have a height, which --freshness tracks.\n")
+}
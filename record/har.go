@@ -0,0 +1,227 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// harVersion is the version of the HAR format that recordings are written in.
+const harVersion = "1.2"
+
+// The subset of the HAR 1.2 format that relays are recorded in.
+// See http://www.softwareishard.com/blog/har-12-spec/.
+type (
+	harFile struct {
+		Log harLog `json:"log"`
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime time.Time   `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		// Comment carries the error reason of a failed relay.
+		Comment string `json:"comment,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string         `json:"method"`
+		URL         string         `json:"url"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		QueryString []harNameValue `json:"queryString"`
+		Cookies     []harNameValue `json:"cookies"`
+		PostData    *harPostData   `json:"postData,omitempty"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	harResponse struct {
+		Status      int            `json:"status"`
+		StatusText  string         `json:"statusText"`
+		HTTPVersion string         `json:"httpVersion"`
+		Headers     []harNameValue `json:"headers"`
+		Cookies     []harNameValue `json:"cookies"`
+		Content     harContent     `json:"content"`
+		RedirectURL string         `json:"redirectURL"`
+		HeadersSize int            `json:"headersSize"`
+		BodySize    int            `json:"bodySize"`
+	}
+
+	harNameValue struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		// Redacted is a custom field, set when redaction changed the text.
+		Redacted bool `json:"_redacted,omitempty"`
+	}
+
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+	}
+
+	// harTimings are in milliseconds, with -1 for phases that do not apply.
+	harTimings struct {
+		Blocked float64 `json:"blocked"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+		SSL     float64 `json:"ssl"`
+	}
+)
+
+// writeHAR writes the entries as a HAR file.
+func writeHAR(writer *bufio.Writer, entries []Entry) error {
+	har := harFile{Log: harLog{
+		Version: harVersion,
+		Creator: harCreator{Name: "relay-util", Version: creatorVersion()},
+		Entries: make([]harEntry, 0, len(entries)),
+	}}
+	for _, entry := range entries {
+		har.Log.Entries = append(har.Log.Entries, toHAR(entry))
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(har)
+}
+
+// readHAR reads the entries of a HAR file, such as one exported by a browser or a gateway.
+func readHAR(reader io.Reader) ([]Entry, error) {
+	var har harFile
+	if err := json.NewDecoder(reader).Decode(&har); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(har.Log.Entries))
+	for i, e := range har.Log.Entries {
+		entry := Entry{
+			ID:              int32(i + 1),
+			StartedAt:       e.StartedDateTime,
+			Method:          e.Request.Method,
+			URL:             e.Request.URL,
+			RequestHeaders:  fromHARHeaders(e.Request.Headers),
+			Status:          e.Response.Status,
+			ResponseHeaders: fromHARHeaders(e.Response.Headers),
+			ResponseBody:    e.Response.Content.Text,
+			Latency:         time.Duration(e.Time * float64(time.Millisecond)),
+			Error:           e.Comment,
+		}
+		if e.Request.PostData != nil {
+			entry.RequestBody = e.Request.PostData.Text
+			entry.RequestBodyRedacted = e.Request.PostData.Redacted
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// toHAR converts an entry to a HAR entry.
+func toHAR(entry Entry) harEntry {
+	e := harEntry{
+		StartedDateTime: entry.StartedAt,
+		Time:            milliseconds(entry.Latency),
+		Request: harRequest{
+			Method:      entry.Method,
+			URL:         entry.URL,
+			HTTPVersion: "HTTP/1.1",
+			Headers:     toHARHeaders(entry.RequestHeaders),
+			QueryString: []harNameValue{},
+			Cookies:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(entry.RequestBody),
+		},
+		Response: harResponse{
+			Status:      entry.Status,
+			StatusText:  http.StatusText(entry.Status),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     toHARHeaders(entry.ResponseHeaders),
+			Cookies:     []harNameValue{},
+			Content: harContent{
+				Size:     len(entry.ResponseBody),
+				MimeType: entry.ResponseHeaders.Get("Content-Type"),
+				Text:     entry.ResponseBody,
+			},
+			HeadersSize: -1,
+			BodySize:    len(entry.ResponseBody),
+		},
+		Timings: harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Send: 0, Wait: milliseconds(entry.Latency), Receive: 0},
+		Comment: entry.Error,
+	}
+	if entry.RequestBody != "" {
+		e.Request.PostData = &harPostData{MimeType: "application/json", Text: entry.RequestBody, Redacted: entry.RequestBodyRedacted}
+	}
+	if u, err := url.Parse(entry.URL); err == nil {
+		for key, values := range u.Query() {
+			for _, value := range values {
+				e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: key, Value: value})
+			}
+		}
+	}
+	return e
+}
+
+// toHARHeaders converts headers to HAR name/value pairs.
+func toHARHeaders(headers http.Header) []harNameValue {
+	pairs := []harNameValue{}
+	for key, values := range headers {
+		for _, value := range values {
+			pairs = append(pairs, harNameValue{Name: key, Value: value})
+		}
+	}
+	return pairs
+}
+
+// fromHARHeaders converts HAR name/value pairs to headers. The HTTP/2 pseudo-headers
+// that browsers export, such as :authority and :path, are dropped, as they are not headers
+// that an HTTP client can send.
+func fromHARHeaders(pairs []harNameValue) http.Header {
+	headers := make(http.Header, len(pairs))
+	for _, pair := range pairs {
+		if !strings.HasPrefix(pair.Name, ":") {
+			headers.Add(pair.Name, pair.Value)
+		}
+	}
+	return headers
+}
+
+// creatorVersion returns the module version of relay-util, which creates the HAR file.
+func creatorVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// milliseconds returns the duration in milliseconds, as HAR timings are.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,66 @@
+package record
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+func TestLoadHARDropsPseudoHeaders(t *testing.T) {
+	entries, err := Load(filepath.Join("testdata", "browser.har"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	entry := entries[0]
+	for _, headers := range []http.Header{entry.RequestHeaders, entry.ResponseHeaders} {
+		for key := range headers {
+			if strings.HasPrefix(key, ":") {
+				t.Errorf("header %s was loaded, want pseudo-headers dropped", key)
+			}
+		}
+	}
+	if got := entry.RequestHeaders.Get("Target-Service-Id"); got != "eth" {
+		t.Errorf("Target-Service-Id = %q, want eth", got)
+	}
+}
+
+func TestReplayHARFromBrowser(t *testing.T) {
+	entries, err := Load(filepath.Join("testdata", "browser.har"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// Headers of recordings loaded by other means may still carry pseudo-headers
+	entries[0].RequestHeaders.Add(":authority", "eth.example.com")
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	u, err := relay.NewRelayUtil(relay.Config{
+		URL:        server.URL,
+		Executions: len(entries),
+		Goroutines: 1,
+		Timeout:    5 * time.Second,
+		Source:     NewSource(entries, 0),
+		Quiet:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewRelayUtil() error = %v", err)
+	}
+	u.SendRelays()
+
+	if result := u.CollectResults()[0]; result.Err {
+		t.Fatalf("relay failed: %s", result.ErrReason)
+	}
+	if got.Get("Target-Service-Id") != "eth" {
+		t.Errorf("headers = %v, want the recorded Target-Service-Id", got)
+	}
+}
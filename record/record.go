@@ -0,0 +1,232 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/redact"
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// Supported recording formats, inferred from the file extension.
+const (
+	FormatHAR    = "har"
+	FormatNDJSON = "ndjson"
+)
+
+type (
+	// Entry is a recorded relay: its request, its response and when it was sent.
+	// Its credentials and secrets are redacted, except in request bodies if the recorder
+	// is configured to leave them unredacted. Durations are encoded in JSON as nanoseconds.
+	Entry struct {
+		ID int32 `json:"id"`
+		// Offset is the time at which the relay was sent, from the start of the recording.
+		Offset         time.Duration `json:"offset"`
+		StartedAt      time.Time     `json:"started_at"`
+		Method         string        `json:"method"`
+		URL            string        `json:"url"`
+		RequestHeaders http.Header   `json:"request_headers,omitempty"`
+		RequestBody    string        `json:"request_body,omitempty"`
+		// RequestBodyRedacted is set when redaction changed the request body, which then cannot be replayed as sent.
+		RequestBodyRedacted bool          `json:"request_body_redacted,omitempty"`
+		Status              int           `json:"status,omitempty"`
+		ResponseHeaders     http.Header   `json:"response_headers,omitempty"`
+		ResponseBody        string        `json:"response_body,omitempty"`
+		Latency             time.Duration `json:"latency"`
+		Attempts            int           `json:"attempts,omitempty"`
+		Error               string        `json:"error,omitempty"`
+	}
+
+	// Config configures what a recorder redacts.
+	Config struct {
+		// UnredactedRequestBodies records request bodies as they were sent, so that they
+		// are replayed exactly, at the cost of writing any secrets they contain to the recording.
+		UnredactedRequestBodies bool
+	}
+
+	// Recorder records the relays of a run, to be saved as HAR or NDJSON.
+	Recorder struct {
+		config  Config
+		mu      sync.Mutex
+		entries []Entry
+	}
+)
+
+// Format returns the recording format of the file, from its extension.
+// Files ending in .har are HAR files, any other file is NDJSON.
+func Format(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".har") {
+		return FormatHAR
+	}
+	return FormatNDJSON
+}
+
+// New creates an empty recorder.
+func New(config Config) *Recorder {
+	return &Recorder{config: config}
+}
+
+// Record redacts and records the exchange of a relay. Its request body is
+// recorded as sent if the recorder is configured to leave request bodies unredacted.
+func (r *Recorder) Record(exchange relay.Exchange) {
+	entry := Entry{
+		ID:              exchange.ID,
+		StartedAt:       exchange.StartedAt,
+		Method:          exchange.Method,
+		URL:             redact.URL(exchange.URL),
+		RequestHeaders:  redact.Headers(exchange.RequestHeaders),
+		RequestBody:     string(exchange.RequestBody),
+		Status:          exchange.Status,
+		ResponseHeaders: redact.Headers(exchange.ResponseHeaders),
+		ResponseBody:    redact.Body(string(exchange.ResponseBody)),
+		Latency:         exchange.Latency,
+		Attempts:        exchange.Attempts,
+		Error:           exchange.Err,
+	}
+	if !r.config.UnredactedRequestBodies {
+		entry.RequestBody = redact.Body(entry.RequestBody)
+		entry.RequestBodyRedacted = entry.RequestBody != string(exchange.RequestBody)
+	}
+	if entry.Method == "" {
+		entry.Method = http.MethodGet
+		if entry.RequestBody != "" {
+			entry.Method = http.MethodPost
+		}
+	}
+	// The trace context is specific to the run, and a replay sends its own
+	entry.RequestHeaders.Del("Traceparent")
+	entry.RequestHeaders.Del(relay.IDHeader)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns the recorded entries in the order they were sent, with their offsets set.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]Entry, len(r.entries))
+	copy(entries, r.entries)
+	SortEntries(entries)
+	return entries
+}
+
+// Len returns the number of recorded entries.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Save writes the recorded entries to the file, as HAR or NDJSON depending on its extension.
+func (r *Recorder) Save(path string) error {
+	return Save(path, r.Entries())
+}
+
+// Save writes entries to the file, as HAR or NDJSON depending on its extension.
+func Save(path string, entries []Entry) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create recording: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if Format(path) == FormatHAR {
+		err = writeHAR(writer, entries)
+	} else {
+		err = writeNDJSON(writer, entries)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+	return file.Close()
+}
+
+// Load reads the entries of a HAR or NDJSON recording, in the order they were sent.
+func Load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	if Format(path) == FormatHAR {
+		entries, err = readHAR(file)
+	} else {
+		entries, err = readNDJSON(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("recording %s has no entries", path)
+	}
+	SortEntries(entries)
+	return entries, nil
+}
+
+// Replayable returns the entries whose request bodies were not changed by redaction,
+// and how many were skipped because they were.
+func Replayable(entries []Entry) ([]Entry, int) {
+	replayable := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.RequestBodyRedacted {
+			replayable = append(replayable, entry)
+		}
+	}
+	return replayable, len(entries) - len(replayable)
+}
+
+// SortEntries sorts the entries by the time they were sent and sets their offsets from the first one.
+func SortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].StartedAt.Before(entries[j].StartedAt) })
+	for i := range entries {
+		entries[i].Offset = entries[i].StartedAt.Sub(entries[0].StartedAt)
+	}
+}
+
+// writeNDJSON writes one entry per line.
+func writeNDJSON(writer *bufio.Writer, entries []Entry) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readNDJSON reads one entry per line, skipping blank lines.
+func readNDJSON(file *os.File) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,82 @@
+package record
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+const (
+	plainBody  = `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`
+	secretBody = `{"jsonrpc":"2.0","id":2,"method":"personal_unlockAccount","params":[{"password":"hunter2"}]}`
+)
+
+// exchange returns the exchange of a relay with the request body.
+func exchange(id int32, body string) relay.Exchange {
+	return relay.Exchange{
+		ID:             id,
+		StartedAt:      time.Unix(1700000000, 0).Add(time.Duration(id) * time.Millisecond),
+		Method:         http.MethodPost,
+		URL:            "http://localhost:8545",
+		RequestHeaders: http.Header{"Content-Type": {"application/json"}},
+		RequestBody:    []byte(body),
+		Status:         http.StatusOK,
+		ResponseBody:   []byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`),
+	}
+}
+
+func TestRecordMarksRedactedRequestBodies(t *testing.T) {
+	recorder := New(Config{})
+	recorder.Record(exchange(1, plainBody))
+	recorder.Record(exchange(2, secretBody))
+
+	entries := recorder.Entries()
+	if entries[0].RequestBodyRedacted || entries[0].RequestBody != plainBody {
+		t.Errorf("entry 1 = %q, redacted %t, want the body as sent", entries[0].RequestBody, entries[0].RequestBodyRedacted)
+	}
+	if !entries[1].RequestBodyRedacted || entries[1].RequestBody == secretBody {
+		t.Errorf("entry 2 = %q, redacted %t, want a redacted body", entries[1].RequestBody, entries[1].RequestBodyRedacted)
+	}
+}
+
+func TestRecordUnredactedRequestBodies(t *testing.T) {
+	recorder := New(Config{UnredactedRequestBodies: true})
+	recorder.Record(exchange(1, secretBody))
+
+	entry := recorder.Entries()[0]
+	if entry.RequestBodyRedacted || entry.RequestBody != secretBody {
+		t.Errorf("entry = %q, redacted %t, want the body as sent", entry.RequestBody, entry.RequestBodyRedacted)
+	}
+	if entry.RequestHeaders.Get("Content-Type") != "application/json" {
+		t.Errorf("request headers = %v, want the recorded headers", entry.RequestHeaders)
+	}
+}
+
+func TestRedactedRequestBodiesAreSkippedOnReplay(t *testing.T) {
+	for _, file := range []string{"traffic.har", "traffic.ndjson"} {
+		t.Run(file, func(t *testing.T) {
+			recorder := New(Config{})
+			recorder.Record(exchange(1, plainBody))
+			recorder.Record(exchange(2, secretBody))
+			path := filepath.Join(t.TempDir(), file)
+			if err := recorder.Save(path); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			entries, err := Load(path)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			replayable, skipped := Replayable(entries)
+			if skipped != 1 || len(replayable) != 1 {
+				t.Fatalf("got %d replayable and %d skipped entries, want 1 and 1", len(replayable), skipped)
+			}
+			if body := string(NewSource(replayable, 0).Request(1).Body); body != plainBody {
+				t.Errorf("replayed body = %q, want %q", body, plainBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package record
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/redact"
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// maxMismatchExamples is the number of mismatches kept as examples in a comparison.
+const maxMismatchExamples = 10
+
+// unreplayedHeaders are the headers of a recorded request that are not sent again,
+// because they are specific to the recorded connection or set by the HTTP client.
+// HTTP/2 pseudo-headers, starting with a colon, are not sent again either.
+var unreplayedHeaders = []string{
+	"Accept-Encoding", "Connection", "Content-Length", "Host", "Keep-Alive",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "User-Agent",
+	"Traceparent", "Tracestate", relay.IDHeader,
+}
+
+type (
+	// Source replays recorded entries as relays, keeping their inter-arrival timing.
+	Source struct {
+		entries []Entry
+		speed   float64
+	}
+
+	// Comparison compares the responses of replayed relays with the recorded ones,
+	// matching JSON-RPC responses by ID.
+	Comparison struct {
+		Relays int `json:"relays"`
+		// Matched relays got the same results or errors as recorded.
+		Matched int `json:"matched"`
+		// Mismatched relays got different results or errors than recorded.
+		Mismatched int `json:"mismatched"`
+		// NewlyFailed relays failed although the recorded ones succeeded, and NewlySucceeded the other way round.
+		NewlyFailed    int        `json:"newly_failed"`
+		NewlySucceeded int        `json:"newly_succeeded"`
+		Examples       []Mismatch `json:"examples,omitempty"`
+	}
+
+	// Mismatch is an example of a replayed relay whose response differs from the recorded one.
+	Mismatch struct {
+		ID     int32  `json:"id"`
+		Method string `json:"method,omitempty"`
+		Reason string `json:"reason"`
+	}
+)
+
+// NewSource creates a source replaying the entries, speed times faster than recorded.
+// A speed of 0 sends them as fast as possible instead.
+func NewSource(entries []Entry, speed float64) *Source {
+	return &Source{entries: entries, speed: speed}
+}
+
+// Request returns the recorded request of the relay. Headers whose values were
+// redacted are not sent again, so credentials must be set again for the replay.
+func (s *Source) Request(id int32) relay.Request {
+	entry := s.entries[id-1]
+
+	headers := make(http.Header, len(entry.RequestHeaders))
+	for key, values := range entry.RequestHeaders {
+		if strings.HasPrefix(key, ":") {
+			continue
+		}
+		for _, value := range values {
+			if value != redact.Mask {
+				headers.Add(key, value)
+			}
+		}
+	}
+	for _, key := range unreplayedHeaders {
+		headers.Del(key)
+	}
+
+	request := relay.Request{Body: []byte(entry.RequestBody), Headers: headers}
+	if s.speed > 0 {
+		request.At = time.Duration(float64(entry.Offset) / s.speed)
+	}
+	return request
+}
+
+// Duration returns how long the replay takes at its speed.
+func (s *Source) Duration() time.Duration {
+	if s.speed <= 0 || len(s.entries) == 0 {
+		return 0
+	}
+	return time.Duration(float64(s.entries[len(s.entries)-1].Offset) / s.speed)
+}
+
+// String describes the replayed traffic.
+func (s *Source) String() string {
+	if s.speed <= 0 {
+		return fmt.Sprintf("%d relays replayed as fast as possible", len(s.entries))
+	}
+	return fmt.Sprintf("%d relays replayed at %gx speed, over %s", len(s.entries), s.speed, s.Duration().Round(time.Millisecond))
+}
+
+// Compare compares the replayed entries with the recorded ones. The replayed entry
+// with ID n is the replay of the nth recorded entry.
+func Compare(recorded, replayed []Entry) Comparison {
+	byID := make(map[int32]Entry, len(replayed))
+	for _, entry := range replayed {
+		byID[entry.ID] = entry
+	}
+
+	var comparison Comparison
+	for i, original := range recorded {
+		id := int32(i + 1)
+		replay, ok := byID[id]
+		if !ok {
+			continue
+		}
+		comparison.Relays++
+
+		recordedFailed, replayFailed := original.Error != "", replay.Error != ""
+		reason := ""
+		switch {
+		case !recordedFailed && replayFailed:
+			comparison.NewlyFailed++
+			reason = "failed: " + replay.Error
+		case recordedFailed && !replayFailed:
+			comparison.NewlySucceeded++
+			reason = "succeeded, recorded as failed: " + original.Error
+		default:
+			reason = compareResponses(original.ResponseBody, replay.ResponseBody)
+		}
+
+		if reason == "" {
+			comparison.Matched++
+			continue
+		}
+		comparison.Mismatched++
+		if len(comparison.Examples) < maxMismatchExamples {
+			comparison.Examples = append(comparison.Examples, Mismatch{ID: id, Method: strings.Join(Methods(original.RequestBody), ","), Reason: reason})
+		}
+	}
+	return comparison
+}
+
+// MatchRate returns the percentage of replayed relays whose responses matched the recorded ones.
+func (c Comparison) MatchRate() float64 {
+	if c.Relays == 0 {
+		return 0
+	}
+	return float64(c.Matched) / float64(c.Relays) * 100
+}
+
+// compareResponses compares two JSON-RPC response bodies, single or batched, by response ID.
+// It returns why they differ, or an empty string if they match.
+func compareResponses(recordedBody, replayedBody string) string {
+	recorded, err := parseResponses(recordedBody)
+	if err != nil {
+		// Without JSON-RPC responses to match, compare the bodies as they are
+		if strings.TrimSpace(recordedBody) != strings.TrimSpace(replayedBody) {
+			return "body differs"
+		}
+		return ""
+	}
+	replayed, err := parseResponses(replayedBody)
+	if err != nil {
+		return "invalid JSON-RPC response: " + err.Error()
+	}
+
+	replayedByID := make(map[string]relay.Response, len(replayed))
+	for _, response := range replayed {
+		replayedByID[response.ID.String()] = response
+	}
+	for _, want := range recorded {
+		got, ok := replayedByID[want.ID.String()]
+		switch {
+		case !ok:
+			return fmt.Sprintf("id %s: no response", want.ID)
+		case want.Error.Code != got.Error.Code:
+			return fmt.Sprintf("id %s: error code %d, recorded %d", want.ID, got.Error.Code, want.Error.Code)
+		case !reflect.DeepEqual(want.Result, got.Result):
+			return fmt.Sprintf("id %s: result %s, recorded %s", want.ID, truncate(got.Result), truncate(want.Result))
+		}
+	}
+	return ""
+}
+
+// parseResponses parses a single or batched JSON-RPC response body.
+func parseResponses(body string) ([]relay.Response, error) {
+	body = strings.TrimSpace(body)
+	if strings.HasPrefix(body, "[") {
+		var responses []relay.Response
+		err := json.Unmarshal([]byte(body), &responses)
+		return responses, err
+	}
+	var response relay.Response
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, err
+	}
+	return []relay.Response{response}, nil
+}
+
+// Methods returns the JSON-RPC methods of a single or batched request body,
+// or nil if it is not JSON.
+func Methods(body string) []string {
+	var requests []struct {
+		Method string `json:"method"`
+	}
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "[") {
+		body = "[" + body + "]"
+	}
+	if err := json.Unmarshal([]byte(body), &requests); err != nil {
+		return nil
+	}
+
+	methods := make([]string, 0, len(requests))
+	for _, request := range requests {
+		methods = append(methods, request.Method)
+	}
+	return methods
+}
+
+// truncate returns a JSON value as a string of up to 60 characters.
+func truncate(value interface{}) string {
+	data, _ := json.Marshal(value)
+	if len(data) > 60 {
+		return string(data[:60]) + "…"
+	}
+	return string(data)
+}
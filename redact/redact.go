@@ -0,0 +1,326 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Mask replaces the value of sensitive headers.
+const Mask = "*****"
+
+// AppIDMask replaces App IDs in URLs.
+const AppIDMask = "******"
+
+// Defaults are always redacted, in addition to the configured rules.
+var (
+	// defaultHeaders are the names of headers whose values are masked.
+	defaultHeaders = []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+		"X-Api-Key", "Api-Key", "X-Auth-Token", "X-Access-Token", "X-Portal-Token", "Portal-Token",
+	}
+	// defaultQueryParams are the names of query parameters whose values are masked.
+	defaultQueryParams = []string{
+		"api_key", "apikey", "api-key", "key", "token", "access_token", "auth", "secret", "password", "signature", "sig",
+	}
+	// defaultPatterns match secrets in any text. If a pattern has groups, only the groups are masked.
+	defaultPatterns = []string{
+		`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]+)`,
+		`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+		`(?i)"(?:password|passphrase|secret|private_?key|api_?key|access_?token|token)"\s*:\s*"([^"]*)"`,
+	}
+)
+
+// urlPattern matches URLs in text, such as the URL of a failed request in an error reason.
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+type (
+	// Config configures what is redacted, in addition to the defaults.
+	Config struct {
+		// Headers are the names of headers whose values are masked.
+		Headers []string
+		// QueryParams are the names of URL query parameters whose values are masked.
+		QueryParams []string
+		// JSONPaths are dotted paths into JSON bodies whose values are masked, eg. params.0.privateKey.
+		// A * segment matches any key or index.
+		JSONPaths []string
+		// Patterns are regular expressions matching secrets in any text.
+		// If a pattern has groups, only the groups are masked.
+		Patterns []string
+	}
+
+	// rules are the compiled redaction rules.
+	rules struct {
+		headers     map[string]bool
+		queryParams map[string]bool
+		jsonPaths   [][]string
+		patterns    []*regexp.Regexp
+		secrets     []string
+	}
+)
+
+var (
+	mu      sync.RWMutex
+	current = mustCompile(Config{})
+)
+
+// Configure sets the redaction rules of all output, in addition to the defaults.
+// The secrets added with AddSecrets are kept.
+func Configure(config Config) error {
+	compiled, err := compile(config)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	compiled.secrets = current.secrets
+	current = compiled
+	return nil
+}
+
+// AddSecrets adds literal values that are masked wherever they appear, such as rotated API keys.
+// Secrets that were already added are not added again. The rules are copied rather than changed
+// in place, as output may be redacted with the current rules at the same time.
+func AddSecrets(secrets ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	updated := *current
+	updated.secrets = slices.Clone(current.secrets)
+	for _, secret := range secrets {
+		if secret != "" && !slices.Contains(updated.secrets, secret) {
+			updated.secrets = append(updated.secrets, secret)
+		}
+	}
+	current = &updated
+}
+
+// compile merges the config with the defaults and compiles it.
+func compile(config Config) (*rules, error) {
+	r := &rules{
+		headers:     make(map[string]bool),
+		queryParams: make(map[string]bool),
+	}
+	for _, header := range append(defaultHeaders, config.Headers...) {
+		r.headers[strings.ToLower(strings.TrimSpace(header))] = true
+	}
+	for _, param := range append(defaultQueryParams, config.QueryParams...) {
+		r.queryParams[strings.ToLower(strings.TrimSpace(param))] = true
+	}
+	for _, path := range config.JSONPaths {
+		path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+		if path == "" {
+			return nil, fmt.Errorf("invalid redaction JSON path: empty path")
+		}
+		r.jsonPaths = append(r.jsonPaths, strings.Split(path, "."))
+	}
+	for _, pattern := range append(defaultPatterns, config.Patterns...) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// mustCompile compiles the config, panicking on error. It is only used for the defaults.
+func mustCompile(config Config) *rules {
+	r, err := compile(config)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// load returns the current rules.
+func load() *rules {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// IsSensitiveHeader returns true if the value of the header must be masked.
+func IsSensitiveHeader(key string) bool {
+	return load().headers[strings.ToLower(key)]
+}
+
+// Header returns the value of the header, masked if the header is sensitive.
+func Header(key, value string) string {
+	if IsSensitiveHeader(key) {
+		return Mask
+	}
+	return Text(value)
+}
+
+// Headers returns a copy of the headers with the values of sensitive headers masked.
+func Headers(headers http.Header) http.Header {
+	masked := make(http.Header, len(headers))
+	for key, values := range headers {
+		for _, value := range values {
+			masked[key] = append(masked[key], Header(key, value))
+		}
+	}
+	return masked
+}
+
+// URL masks the password, the App ID and sensitive query parameters in the URL.
+func URL(urlString string) string {
+	return load().url(urlString)
+}
+
+func (r *rules) url(urlString string) string {
+	// Parse the URL
+	u, err := url.Parse(urlString)
+	if err != nil {
+		return r.text(urlString) // If there's an error parsing, mask what can be masked in the original string
+	}
+
+	maskedURL := u.Scheme + "://"
+
+	// Mask the password if it exists
+	if u.User != nil {
+		username := u.User.Username()
+		_, hasPassword := u.User.Password()
+		if hasPassword {
+			// Build userInfo with masked password
+			maskedURL += username + ":******@"
+		} else {
+			// Include username only if there's no password
+			maskedURL += username + "@"
+		}
+	}
+
+	// Add host
+	maskedURL += u.Host
+
+	// Mask the AppID if it's present in the path, unless it is a placeholder such as {app_id}
+	parts := strings.Split(u.Path, "/")
+	if len(parts) > 0 {
+		lastPartIndex := len(parts) - 1
+		if len(parts[lastPartIndex]) == 8 && !strings.HasPrefix(parts[lastPartIndex], "{") {
+			parts[lastPartIndex] = AppIDMask
+		}
+		u.Path = strings.Join(parts, "/")
+	}
+
+	// Add path
+	maskedURL += u.Path
+
+	// Add the query, with the values of sensitive parameters masked
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			key, _, hasValue := strings.Cut(param, "=")
+			if name, err := url.QueryUnescape(key); err == nil && hasValue && r.queryParams[strings.ToLower(name)] {
+				params[i] = key + "=" + Mask
+			}
+		}
+		maskedURL += "?" + strings.Join(params, "&")
+	}
+
+	return r.secretsAndPatterns(maskedURL)
+}
+
+// Body masks the values at the configured JSON paths of a JSON body, and secrets anywhere in it.
+// Bodies that are not JSON are masked as text.
+func Body(body string) string {
+	r := load()
+	if len(r.jsonPaths) > 0 {
+		decoder := json.NewDecoder(strings.NewReader(body))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err == nil {
+			masked := false
+			for _, path := range r.jsonPaths {
+				value = maskJSONPath(value, path, &masked)
+			}
+			if masked {
+				var buf bytes.Buffer
+				encoder := json.NewEncoder(&buf)
+				encoder.SetEscapeHTML(false)
+				if err := encoder.Encode(value); err == nil {
+					body = strings.TrimSuffix(buf.String(), "\n")
+				}
+			}
+		}
+	}
+	return r.text(body)
+}
+
+// maskJSONPath masks the values at the path of a decoded JSON value.
+func maskJSONPath(value any, path []string, masked *bool) any {
+	if len(path) == 0 {
+		*masked = true
+		return Mask
+	}
+
+	segment, rest := path[0], path[1:]
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if segment == "*" || segment == key {
+				v[key] = maskJSONPath(child, rest, masked)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			if segment == "*" || segment == strconv.Itoa(i) {
+				v[i] = maskJSONPath(child, rest, masked)
+			}
+		}
+	}
+	return value
+}
+
+// Text masks the URLs, secrets and pattern matches in any text, such as an error reason.
+func Text(text string) string {
+	return load().text(text)
+}
+
+func (r *rules) text(text string) string {
+	text = urlPattern.ReplaceAllStringFunc(text, r.url)
+	return r.secretsAndPatterns(text)
+}
+
+// secretsAndPatterns masks the literal secrets and the pattern matches in the text.
+func (r *rules) secretsAndPatterns(text string) string {
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, Mask)
+	}
+	for _, pattern := range r.patterns {
+		text = maskMatches(pattern, text)
+	}
+	return text
+}
+
+// maskMatches masks the matches of the pattern in the text, or only their groups if it has any.
+func maskMatches(pattern *regexp.Regexp, text string) string {
+	if pattern.NumSubexp() == 0 {
+		return pattern.ReplaceAllLiteralString(text, Mask)
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		for group := 1; group <= pattern.NumSubexp(); group++ {
+			start, end := match[2*group], match[2*group+1]
+			if start < last || start < 0 {
+				continue
+			}
+			out.WriteString(text[last:start])
+			out.WriteString(Mask)
+			last = end
+		}
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
@@ -0,0 +1,173 @@
+package relay
+
+import (
+	"container/heap"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/redact"
+)
+
+// maxCapturedBodyLength is the length at which the bodies of captured relays are truncated.
+const maxCapturedBodyLength = 16 * 1024
+
+type (
+	// CaptureConfig sets how many relays are captured with their full request and response.
+	CaptureConfig struct {
+		// Slowest is the number of slowest successful relays captured.
+		Slowest int
+		// Failed is the number of failed relays captured, sampled at random across the run.
+		Failed int
+	}
+
+	// Captures are the relays captured with their full request and response, with their secrets masked.
+	Captures struct {
+		// Slowest are the slowest successful relays, the slowest first.
+		Slowest []CapturedRelay `json:"slowest,omitempty"`
+		// Failed are a sample of the failed relays, in the order they were sent.
+		Failed []CapturedRelay `json:"failed,omitempty"`
+		// FailedRelays is the number of failed relays that were sampled from.
+		FailedRelays int `json:"failed_relays"`
+	}
+
+	// CapturedRelay is the request and response of the last attempt of a relay.
+	// Durations are encoded in JSON as nanoseconds.
+	CapturedRelay struct {
+		ID              int32         `json:"id"`
+		StartedAt       time.Time     `json:"started_at"`
+		Method          string        `json:"method"`
+		URL             string        `json:"url"`
+		RequestHeaders  http.Header   `json:"request_headers,omitempty"`
+		RequestBody     string        `json:"request_body,omitempty"`
+		Status          int           `json:"status,omitempty"`
+		ResponseHeaders http.Header   `json:"response_headers,omitempty"`
+		ResponseBody    string        `json:"response_body,omitempty"`
+		Latency         time.Duration `json:"latency"`
+		Timings         PhaseTimings  `json:"timings"`
+		Attempts        int           `json:"attempts"`
+		TraceID         string        `json:"trace_id,omitempty"`
+		Error           string        `json:"error,omitempty"`
+	}
+
+	// capturer keeps the slowest relays in a min-heap and samples the failed relays
+	// with a reservoir, so that a run never holds more than the configured relays.
+	capturer struct {
+		mu           sync.Mutex
+		config       CaptureConfig
+		slowest      slowestHeap
+		failed       []CapturedRelay
+		failedRelays int
+		random       *rand.Rand
+	}
+
+	// slowestHeap is a min-heap of relays by latency, whose root is the fastest of the slowest relays.
+	slowestHeap []CapturedRelay
+)
+
+// Enabled returns true if any relay is captured.
+func (c CaptureConfig) Enabled() bool {
+	return c.Slowest > 0 || c.Failed > 0
+}
+
+// newCapturer creates a capturer, or returns nil if no relay is captured.
+func newCapturer(config CaptureConfig) *capturer {
+	if !config.Enabled() {
+		return nil
+	}
+	return &capturer{config: config, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// capture keeps the exchange of a relay if it is one of the slowest or is sampled among the failed relays.
+// Exchanges are only redacted once they are kept.
+func (c *capturer) capture(exchange *Exchange, traceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exchange.Err != "" {
+		// Reservoir sampling keeps every failed relay with the same probability
+		c.failedRelays++
+		switch {
+		case len(c.failed) < c.config.Failed:
+			c.failed = append(c.failed, newCapturedRelay(exchange, traceID))
+		case c.config.Failed > 0:
+			if i := c.random.Intn(c.failedRelays); i < c.config.Failed {
+				c.failed[i] = newCapturedRelay(exchange, traceID)
+			}
+		}
+		return
+	}
+
+	switch {
+	case len(c.slowest) < c.config.Slowest:
+		heap.Push(&c.slowest, newCapturedRelay(exchange, traceID))
+	case c.config.Slowest > 0 && exchange.Latency > c.slowest[0].Latency:
+		c.slowest[0] = newCapturedRelay(exchange, traceID)
+		heap.Fix(&c.slowest, 0)
+	}
+}
+
+// captures returns the captured relays.
+func (c *capturer) captures() *Captures {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	captures := &Captures{
+		Slowest:      append([]CapturedRelay(nil), c.slowest...),
+		Failed:       append([]CapturedRelay(nil), c.failed...),
+		FailedRelays: c.failedRelays,
+	}
+	sort.Slice(captures.Slowest, func(i, j int) bool { return captures.Slowest[i].Latency > captures.Slowest[j].Latency })
+	sort.Slice(captures.Failed, func(i, j int) bool { return captures.Failed[i].ID < captures.Failed[j].ID })
+	return captures
+}
+
+// Captures returns the relays captured with their full request and response,
+// or nil if relays are not captured.
+func (u *Util) Captures() *Captures {
+	if u.capturer == nil {
+		return nil
+	}
+	return u.capturer.captures()
+}
+
+// newCapturedRelay redacts the exchange of a relay and truncates its bodies.
+func newCapturedRelay(exchange *Exchange, traceID string) CapturedRelay {
+	return CapturedRelay{
+		ID:              exchange.ID,
+		StartedAt:       exchange.StartedAt,
+		Method:          exchange.Method,
+		URL:             redact.URL(exchange.URL),
+		RequestHeaders:  redact.Headers(exchange.RequestHeaders),
+		RequestBody:     truncateBody(redact.Body(string(exchange.RequestBody))),
+		Status:          exchange.Status,
+		ResponseHeaders: redact.Headers(exchange.ResponseHeaders),
+		ResponseBody:    truncateBody(redact.Body(string(exchange.ResponseBody))),
+		Latency:         exchange.Latency,
+		Timings:         exchange.Timings,
+		Attempts:        exchange.Attempts,
+		TraceID:         traceID,
+		Error:           exchange.Err,
+	}
+}
+
+// truncateBody truncates a captured body at maxCapturedBodyLength.
+func truncateBody(body string) string {
+	if len(body) > maxCapturedBodyLength {
+		return body[:maxCapturedBodyLength] + "…"
+	}
+	return body
+}
+
+func (h slowestHeap) Len() int           { return len(h) }
+func (h slowestHeap) Less(i, j int) bool { return h[i].Latency < h[j].Latency }
+func (h slowestHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *slowestHeap) Push(x any)        { *h = append(*h, x.(CapturedRelay)) }
+func (h *slowestHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
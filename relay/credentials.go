@@ -0,0 +1,163 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported values for Config.CredentialOrder.
+const (
+	CredentialOrderRoundRobin = "round-robin"
+	CredentialOrderRandom     = "random"
+)
+
+// AppIDPlaceholder is replaced with the app ID of each relay in the URL.
+// If the URL does not contain it, the app ID is appended to the URL path.
+const AppIDPlaceholder = "{app_id}"
+
+type (
+	// Credential is one of the credentials that relays are rotated across,
+	// to simulate many applications at once.
+	Credential struct {
+		// Name identifies the credential in the results without revealing it, eg. keys.txt:3.
+		Name string
+		// Header is set to Value on each relay sent with the credential, if set.
+		Header, Value string
+		// AppID is put in the URL path of each relay sent with the credential, if set.
+		AppID string
+	}
+
+	// CredentialStats are the statistics of the relays sent with a credential.
+	CredentialStats struct {
+		Credential string `json:"credential"`
+		Stats      Stats  `json:"stats"`
+		// Throttled is the number of relays that failed because they were throttled.
+		Throttled int `json:"throttled"`
+	}
+
+	// credentialKey is the context key of the credential of the relay being sent.
+	credentialKey struct{}
+)
+
+// LoadAPIKeys reads a file of API keys, one per line, to be sent in the header.
+// Blank lines and lines starting with # are ignored.
+func LoadAPIKeys(path, header string) ([]Credential, error) {
+	return loadCredentials(path, func(name, value string) Credential {
+		return Credential{Name: name, Header: header, Value: value}
+	})
+}
+
+// LoadAppIDs reads a file of app IDs, one per line, to be put in the URL path.
+// Blank lines and lines starting with # are ignored.
+func LoadAppIDs(path string) ([]Credential, error) {
+	return loadCredentials(path, func(name, value string) Credential {
+		return Credential{Name: name, AppID: value}
+	})
+}
+
+// loadCredentials reads a file of credentials, one per line, named after the file and their line number.
+func loadCredentials(path string, credential func(name, value string) Credential) ([]Credential, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	defer file.Close()
+
+	var credentials []Credential
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" || strings.HasPrefix(value, "#") {
+			continue
+		}
+		credentials = append(credentials, credential(fmt.Sprintf("%s:%d", filepath.Base(path), line), value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("credentials file %s has no credentials", path)
+	}
+	return credentials, nil
+}
+
+// appIDURL returns the URL of the relays sent with the app ID.
+func appIDURL(rawURL, appID string) string {
+	if strings.Contains(rawURL, AppIDPlaceholder) {
+		return strings.ReplaceAll(rawURL, AppIDPlaceholder, appID)
+	}
+
+	// Append the app ID to the path, before the query
+	path, query, hasQuery := strings.Cut(rawURL, "?")
+	rawURL = strings.TrimSuffix(path, "/") + "/" + appID
+	if hasQuery {
+		rawURL += "?" + query
+	}
+	return rawURL
+}
+
+// credential picks the credential of a relay, in turn or at random. It returns -1 without credentials.
+func (u *Util) credential(id int32) int {
+	switch {
+	case len(u.Credentials) == 0:
+		return -1
+	case u.CredentialOrder == CredentialOrderRandom:
+		return rand.Intn(len(u.Credentials))
+	default:
+		return int(id-1) % len(u.Credentials)
+	}
+}
+
+// credentialFromContext returns the index of the credential of the relay being sent, or -1 without credentials.
+func credentialFromContext(ctx context.Context) int {
+	if i, ok := ctx.Value(credentialKey{}).(int); ok {
+		return i
+	}
+	return -1
+}
+
+// CredentialBreakdown calculates the statistics of the relays sent with each credential,
+// in the order the credentials were first used. It returns nil if no credentials were rotated.
+func CredentialBreakdown(results []RelayResult, execTime time.Duration) []CredentialStats {
+	byCredential := make(map[string][]RelayResult)
+	firstID := make(map[string]int32)
+	var names []string
+	for _, result := range results {
+		if result.Credential == "" {
+			continue
+		}
+		if id, ok := firstID[result.Credential]; !ok {
+			names = append(names, result.Credential)
+			firstID[result.Credential] = result.ID
+		} else {
+			firstID[result.Credential] = min(id, result.ID)
+		}
+		byCredential[result.Credential] = append(byCredential[result.Credential], result)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool { return firstID[names[i]] < firstID[names[j]] })
+
+	breakdown := make([]CredentialStats, 0, len(names))
+	for _, name := range names {
+		stats := CredentialStats{
+			Credential: name,
+			Stats:      NewStats(byCredential[name], execTime),
+		}
+		for _, result := range byCredential[name] {
+			if result.Err && result.Throttled {
+				stats.Throttled++
+			}
+		}
+		breakdown = append(breakdown, stats)
+	}
+	return breakdown
+}
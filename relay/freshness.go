@@ -0,0 +1,186 @@
+package relay
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStaleThreshold is the number of blocks behind the highest block seen that a response may be before it is stale.
+const DefaultStaleThreshold = 2
+
+// Lags of at least maxLagBucket blocks share the last bucket of the lag distribution.
+const maxLagBucket = 10
+
+// maxStaleExamples is the number of stale responses listed in the freshness of a run.
+const maxStaleExamples = 10
+
+type (
+	// Freshness is how far behind the chain head the responses of a run were. Each response
+	// is compared to the highest block seen by the time it was received, so that the chain
+	// advancing during the run does not make earlier responses look stale.
+	Freshness struct {
+		// Threshold is the number of blocks behind that a response may be before it is stale.
+		Threshold uint64 `json:"threshold"`
+		// Responses is the number of successful responses with a block height.
+		Responses int `json:"responses"`
+		// Undecoded is the number of successful responses without a block height.
+		Undecoded    int    `json:"undecoded"`
+		LowestBlock  uint64 `json:"lowest_block"`
+		HighestBlock uint64 `json:"highest_block"`
+		// Stale responses are soft failures: they succeeded, but behind the threshold.
+		Stale     int     `json:"stale"`
+		StaleRate float64 `json:"stale_rate"`
+		P50Lag    uint64  `json:"p50_lag"`
+		P90Lag    uint64  `json:"p90_lag"`
+		P99Lag    uint64  `json:"p99_lag"`
+		MaxLag    uint64  `json:"max_lag"`
+		// Distribution counts the responses by how many blocks behind they were.
+		Distribution []LagBucket `json:"distribution"`
+		// Examples are the first stale responses, in the order they were received.
+		Examples []StaleResponse `json:"examples,omitempty"`
+	}
+
+	// LagBucket is the number of responses that were Lag blocks behind, or more if OrMore is set.
+	LagBucket struct {
+		Lag    uint64  `json:"lag"`
+		OrMore bool    `json:"or_more,omitempty"`
+		Count  int     `json:"count"`
+		Share  float64 `json:"share"`
+	}
+
+	// StaleResponse is a response that was more blocks behind than the threshold.
+	StaleResponse struct {
+		ID         int32  `json:"id"`
+		Block      uint64 `json:"block"`
+		Highest    uint64 `json:"highest"`
+		Lag        uint64 `json:"lag"`
+		Credential string `json:"credential,omitempty"`
+		// Headers are the captured headers of the response, such as the supplier that served it.
+		Headers map[string]string `json:"headers,omitempty"`
+	}
+)
+
+// Freshness returns how far behind the chain head the responses of the run were,
+// or nil if freshness is not tracked.
+func (u *Util) Freshness() *Freshness {
+	if !u.TrackFreshness {
+		return nil
+	}
+	return NewFreshness(u.CollectResults(), u.StaleThreshold)
+}
+
+// NewFreshness calculates how far behind the highest block seen each successful response
+// with a block height was, in the order the responses were received. Responses are stale
+// past the threshold.
+func NewFreshness(results []RelayResult, threshold uint64) *Freshness {
+	type response struct {
+		result     RelayResult
+		block      uint64
+		receivedAt time.Time
+	}
+
+	freshness := &Freshness{Threshold: threshold}
+	var responses []response
+	for _, result := range results {
+		if result.Err {
+			continue
+		}
+		if !result.HasHeight {
+			freshness.Undecoded++
+			continue
+		}
+		receivedAt := result.StartedAt.Add(time.Duration(result.Latency) * time.Millisecond)
+		responses = append(responses, response{result: result, block: result.Height, receivedAt: receivedAt})
+	}
+	freshness.Responses = len(responses)
+	if len(responses) == 0 {
+		return freshness
+	}
+	sort.SliceStable(responses, func(i, j int) bool { return responses[i].receivedAt.Before(responses[j].receivedAt) })
+
+	counts := make([]int, maxLagBucket+1)
+	lags := make([]uint64, 0, len(responses))
+	var highest uint64
+	freshness.LowestBlock = responses[0].block
+	for _, r := range responses {
+		highest = max(highest, r.block)
+		freshness.LowestBlock = min(freshness.LowestBlock, r.block)
+		lag := highest - r.block
+		lags = append(lags, lag)
+		counts[min(lag, maxLagBucket)]++
+
+		if lag > threshold {
+			freshness.Stale++
+			if len(freshness.Examples) < maxStaleExamples {
+				freshness.Examples = append(freshness.Examples, StaleResponse{
+					ID:         r.result.ID,
+					Block:      r.block,
+					Highest:    highest,
+					Lag:        lag,
+					Credential: r.result.Credential,
+					Headers:    r.result.Headers,
+				})
+			}
+		}
+	}
+	freshness.HighestBlock = highest
+	freshness.StaleRate = float64(freshness.Stale) / float64(len(responses)) * 100
+
+	for lag, count := range counts {
+		if count == 0 {
+			continue
+		}
+		freshness.Distribution = append(freshness.Distribution, LagBucket{
+			Lag:    uint64(lag),
+			OrMore: lag == maxLagBucket,
+			Count:  count,
+			Share:  float64(count) / float64(len(responses)) * 100,
+		})
+	}
+
+	sort.Slice(lags, func(i, j int) bool { return lags[i] < lags[j] })
+	freshness.P50Lag = percentileLag(lags, 0.5)
+	freshness.P90Lag = percentileLag(lags, 0.9)
+	freshness.P99Lag = percentileLag(lags, 0.99)
+	freshness.MaxLag = lags[len(lags)-1]
+	return freshness
+}
+
+// BlockHeight decodes the block height of a result, such as the result of eth_blockNumber
+// or getSlot, or the number of a block returned by eth_getBlockByNumber. Heights may be
+// hex quantities, decimal strings or numbers.
+func BlockHeight(body string) (uint64, bool) {
+	var result any
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		return 0, false
+	}
+	if object, ok := result.(map[string]any); ok {
+		result = object["number"]
+	}
+
+	switch v := result.(type) {
+	case string:
+		if hex, ok := strings.CutPrefix(v, "0x"); ok {
+			height, err := strconv.ParseUint(hex, 16, 64)
+			return height, err == nil
+		}
+		height, err := strconv.ParseUint(v, 10, 64)
+		return height, err == nil
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return 0, false
+		}
+		return uint64(v), true
+	}
+	return 0, false
+}
+
+// percentileLag returns the nearest-rank percentile p of the sorted lags.
+func percentileLag(sorted []uint64, p float64) uint64 {
+	index := int(math.Ceil(float64(len(sorted))*p)) - 1
+	return sorted[max(index, 0)]
+}
@@ -0,0 +1,67 @@
+package relay
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/redact"
+)
+
+// MissingHeaderValue is the value that relays whose response lacks a captured header are grouped by.
+const MissingHeaderValue = "(missing)"
+
+// HeaderValueStats are the statistics of the relays whose response had a value of a captured header,
+// such as the supplier or node that served them.
+type HeaderValueStats struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
+	Stats  Stats  `json:"stats"`
+}
+
+// capturedHeaders returns the values of the captured headers of a response, with their secrets masked.
+// Headers with several values are joined with commas.
+func (u *Util) capturedHeaders(httpResp *http.Response) map[string]string {
+	if len(u.CaptureHeaders) == 0 || httpResp == nil {
+		return nil
+	}
+	values := make(map[string]string, len(u.CaptureHeaders))
+	for _, header := range u.CaptureHeaders {
+		if value := strings.Join(httpResp.Header.Values(header), ", "); value != "" {
+			values[http.CanonicalHeaderKey(header)] = redact.Header(header, value)
+		}
+	}
+	return values
+}
+
+// HeaderBreakdown calculates the statistics of the relays by the value of each captured header,
+// in the order the headers are given and then from the most to the least relays. Relays whose
+// response lacked a header, such as relays that timed out, are grouped as MissingHeaderValue.
+func HeaderBreakdown(results []RelayResult, headers []string, execTime time.Duration) []HeaderValueStats {
+	var breakdown []HeaderValueStats
+	for _, header := range headers {
+		header = http.CanonicalHeaderKey(header)
+		byValue := make(map[string][]RelayResult)
+		for _, result := range results {
+			value, ok := result.Headers[header]
+			if !ok {
+				value = MissingHeaderValue
+			}
+			byValue[value] = append(byValue[value], result)
+		}
+
+		values := make([]HeaderValueStats, 0, len(byValue))
+		for value, valueResults := range byValue {
+			values = append(values, HeaderValueStats{Header: header, Value: value, Stats: NewStats(valueResults, execTime)})
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Stats.TotalRelays != values[j].Stats.TotalRelays {
+				return values[i].Stats.TotalRelays > values[j].Stats.TotalRelays
+			}
+			return values[i].Value < values[j].Value
+		})
+		breakdown = append(breakdown, values...)
+	}
+	return breakdown
+}
@@ -0,0 +1,138 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minThrottlePause is the initial pause of the adaptive mode when
+	// a throttled response does not say how long to back off for.
+	minThrottlePause = 100 * time.Millisecond
+	// maxThrottlePause caps the pause of the adaptive mode.
+	maxThrottlePause = 30 * time.Second
+)
+
+// rateLimitRPCCodes are the JSON-RPC error codes used to signal rate limiting.
+// -32005 is the "limit exceeded" code of EIP-1474.
+var rateLimitRPCCodes = map[int]bool{
+	-32005: true,
+	429:    true,
+}
+
+// rateLimitMessages are lowercase fragments of JSON-RPC error messages used to signal rate limiting.
+var rateLimitMessages = []string{
+	"rate limit",
+	"too many requests",
+	"request limit",
+}
+
+// throttleGate pauses all senders in adaptive mode after a throttled response.
+type throttleGate struct {
+	mu          sync.Mutex
+	until       time.Time
+	pause       time.Duration
+	totalPaused time.Duration
+}
+
+// wait blocks until the current throttling pause, if any, is over.
+func (g *throttleGate) wait() {
+	g.mu.Lock()
+	delay := time.Until(g.until)
+	g.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// throttled pauses sending for the duration requested by the endpoint or,
+// if none was given, for an exponentially growing duration.
+func (g *throttleGate) throttled(retryAfter time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pause := retryAfter
+	if pause <= 0 {
+		g.pause = min(max(g.pause*2, minThrottlePause), maxThrottlePause)
+		pause = g.pause
+	}
+
+	until := time.Now().Add(min(pause, maxThrottlePause))
+	if until.After(g.until) {
+		// Only count the part of the pause that extends the current one
+		if g.until.After(time.Now()) {
+			g.totalPaused += until.Sub(g.until)
+		} else {
+			g.totalPaused += time.Until(until)
+		}
+		g.until = until
+	}
+}
+
+// ok resets the exponential pause after a relay is not throttled.
+func (g *throttleGate) ok() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pause = 0
+}
+
+// paused returns the total time sending was paused for.
+func (g *throttleGate) paused() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.totalPaused
+}
+
+// isThrottled returns true if the attempt was rejected by rate limiting.
+func (a attempt) isThrottled() bool {
+	if a.statusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	var rpcErr RelayError
+	if !errors.As(a.err, &rpcErr) {
+		return false
+	}
+	if rateLimitRPCCodes[rpcErr.Code] {
+		return true
+	}
+	message := strings.ToLower(rpcErr.Message)
+	for _, fragment := range rateLimitMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRetryAfter returns how long the response asks the client to wait before
+// sending again, from the Retry-After or rate limit reset headers, or zero if it does not say.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(value); err == nil {
+			return date.Sub(now)
+		}
+	}
+
+	for _, key := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		value, err := strconv.ParseFloat(header.Get(key), 64)
+		if err != nil {
+			continue
+		}
+		// Some endpoints return a Unix timestamp rather than a number of seconds
+		if value > 1e9 {
+			return time.Unix(int64(value), 0).Sub(now)
+		}
+		return time.Duration(value * float64(time.Second))
+	}
+
+	return 0
+}
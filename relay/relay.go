@@ -2,10 +2,16 @@ package relay
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,8 +19,19 @@ import (
 
 	"github.com/cheggaaa/pb/v3"
 	"github.com/fatih/color"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/commoddity/relay-util/v2/redact"
 )
 
+// IDHeader is the request header carrying the relay ID when relays are sent through a local proxy.
+const IDHeader = "X-Relay-Util-Id"
+
 type (
 	ID struct {
 		string   string
@@ -35,11 +52,28 @@ type (
 	}
 
 	RelayResult struct {
-		ID          int32
-		Err         bool
-		ErrReason   string
-		SuccessBody string
-		Latency     int32
+		ID                  int32
+		StartedAt           time.Time
+		Err                 bool
+		ErrReason           string
+		ErrCode             int
+		SuccessBody         string
+		Latency             int32
+		FirstAttemptLatency int32
+		Attempts            int
+		Throttled           bool
+		ThrottledAttempts   int
+		Timings             PhaseTimings
+		TraceID             string
+		// Faults are the faults injected into the attempts of the relay by the chaos proxy.
+		Faults []string
+		// Credential is the name of the credential the relay was sent with, if credentials were rotated.
+		Credential string
+		// Height is the block height of the result, if HasHeight is set.
+		Height    uint64
+		HasHeight bool
+		// Headers are the values of the captured headers of the last response, by canonical header name.
+		Headers map[string]string
 	}
 
 	Config struct {
@@ -51,6 +85,51 @@ type (
 		Wait          time.Duration
 		Timeout       time.Duration
 		SuccessBodies bool
+		Quiet         bool
+		Transport     TransportConfig
+		Retry         RetryConfig
+		Adaptive      bool
+		Tracer        trace.Tracer
+		// Via is the URL of a local proxy, such as the chaos proxy, that relays are sent
+		// through instead of the URL, keeping the path and query of the URL.
+		// Each request then carries its relay ID in the IDHeader header.
+		Via string
+		// Auth sets the credentials of each request. Credentials it sets override the headers.
+		Auth Authenticator
+		// Credentials are rotated across relays, in CredentialOrder, to simulate many applications.
+		Credentials     []Credential
+		CredentialOrder string
+		// Source provides the request of each relay instead of Body, if set.
+		Source Source
+		// Recorder records the request and response of each relay, if set.
+		Recorder Recorder
+		// TrackFreshness tracks how many blocks behind the highest block seen each response is.
+		// Responses more than StaleThreshold blocks behind are stale.
+		TrackFreshness bool
+		StaleThreshold uint64
+		// CaptureHeaders are the response headers whose values are kept in the result of each relay,
+		// such as the supplier or node that served it.
+		CaptureHeaders []string
+		// IDOffset is added to the IDs of the relays, so that the relays of a run split
+		// across several processes, such as distributed agents, have distinct IDs.
+		IDOffset int32
+		// Capture keeps the slowest and a sample of the failed relays with their full request and response.
+		Capture CaptureConfig
+		// Decoder validates the result of each relay and decodes its block height, if set.
+		// Otherwise, block heights are decoded as EVM quantities when freshness is tracked.
+		Decoder ResultDecoder
+	}
+
+	// Authenticator sets the credentials of each request, such as its Authorization header.
+	Authenticator interface {
+		Authenticate(req *http.Request, body []byte) error
+	}
+
+	// ResultDecoder validates the results of requests whose results have a known shape,
+	// such as the methods of a chain preset. A relay whose result is invalid fails.
+	ResultDecoder interface {
+		// Decode validates the result of the request and returns its block height, if it has one.
+		Decode(request []byte, result string) (height uint64, hasHeight bool, err error)
 	}
 
 	Util struct {
@@ -66,10 +145,55 @@ type (
 		Timeout           time.Duration
 		ExecTime          time.Duration
 		SuccessBodies     bool
+		Quiet             bool
 		IsBatch           bool
+		Transport         TransportConfig
+		Retry             RetryConfig
+		Adaptive          bool
+		ThrottlePause     time.Duration
+		Tracer            trace.Tracer
+		Via               string
+		Auth              Authenticator
+		Credentials       []Credential
+		CredentialOrder   string
+		Source            Source
+		Recorder          Recorder
+		TrackFreshness    bool
+		StaleThreshold    uint64
+		Decoder           ResultDecoder
+		Capture           CaptureConfig
+		CaptureHeaders    []string
+		IDOffset          int32
 		ResultChan        chan RelayResult
+		Results           []RelayResult
+
+		requestURL string
+		// credentialURLs are the request URLs of the credentials, which differ when they carry an app ID.
+		credentialURLs []string
+		retryPolicy    retryPolicy
+		throttle       *throttleGate
+		capturer       *capturer
+		// startTime is the start of the run, which the relays of a Source are scheduled from.
+		startTime time.Time
 	}
 
+	// attempt holds the outcome of a single attempt at sending a relay.
+	attempt struct {
+		successBody string
+		err         error
+		statusCode  int
+		retryAfter  time.Duration
+		latency     time.Duration
+		timings     PhaseTimings
+		exchange    *Exchange
+		headers     map[string]string
+		height      uint64
+		hasHeight   bool
+	}
+
+	// relayIDKey is the context key of the ID of the relay being sent.
+	relayIDKey struct{}
+
 	goroutinesConfig struct {
 		goroutines int
 		delay      time.Duration
@@ -77,39 +201,114 @@ type (
 )
 
 // NewRelayUtil creates a new instance of the Relay Util.
-func NewRelayUtil(config Config) *Util {
+func NewRelayUtil(config Config) (*Util, error) {
+	switch config.CredentialOrder {
+	case "", CredentialOrderRoundRobin, CredentialOrderRandom:
+	default:
+		return nil, fmt.Errorf("invalid credential order %q, must be %s or %s", config.CredentialOrder, CredentialOrderRoundRobin, CredentialOrderRandom)
+	}
+
+	requestURL, transport := config.URL, config.Transport
+	credentialURLs := make([]string, len(config.Credentials))
+	for i, credential := range config.Credentials {
+		// The rotated credentials are secrets wherever they show up, such as in error reasons
+		redact.AddSecrets(credential.Value, credential.AppID)
+		credentialURLs[i] = requestURL
+		if credential.AppID != "" {
+			credentialURLs[i] = appIDURL(requestURL, credential.AppID)
+		}
+	}
+	if config.Via != "" {
+		var err error
+		if requestURL, err = viaURL(config.URL, config.Via); err != nil {
+			return nil, err
+		}
+		for i := range credentialURLs {
+			if credentialURLs[i], err = viaURL(credentialURLs[i], config.Via); err != nil {
+				return nil, err
+			}
+		}
+		// The local proxy is reached over plain HTTP/1.1, so only the connection settings apply
+		transport = TransportConfig{
+			MaxConnsPerHost:  transport.MaxConnsPerHost,
+			MaxIdleConns:     transport.MaxIdleConns,
+			DisableKeepAlive: transport.DisableKeepAlive,
+		}
+	}
+
+	httpClient, err := NewHTTPClient(transport, config.Timeout, config.Goroutines)
+	if err != nil {
+		return nil, err
+	}
+
+	retryPolicy, err := newRetryPolicy(config.Retry)
+	if err != nil {
+		return nil, err
+	}
+
 	util := &Util{
-		HTTPClient:    &http.Client{Timeout: config.Timeout},
-		ResultChan:    make(chan RelayResult, config.Executions),
-		URL:           config.URL,
-		Body:          config.Body,
-		Headers:       config.Headers,
-		Executions:    config.Executions,
-		Goroutines:    config.Goroutines,
-		Wait:          config.Wait,
-		Timeout:       config.Timeout,
-		SuccessBodies: config.SuccessBodies,
-		IsBatch:       json.Valid(config.Body) && strings.HasPrefix(strings.TrimSpace(string(config.Body)), "["),
+		HTTPClient:      httpClient,
+		ResultChan:      make(chan RelayResult, config.Executions),
+		URL:             config.URL,
+		Body:            config.Body,
+		Headers:         config.Headers,
+		Executions:      config.Executions,
+		Goroutines:      config.Goroutines,
+		Wait:            config.Wait,
+		Timeout:         config.Timeout,
+		SuccessBodies:   config.SuccessBodies,
+		Quiet:           config.Quiet,
+		IsBatch:         isBatch(config.Body),
+		Transport:       config.Transport,
+		Retry:           config.Retry,
+		Adaptive:        config.Adaptive,
+		Tracer:          config.Tracer,
+		Via:             config.Via,
+		Auth:            config.Auth,
+		Credentials:     config.Credentials,
+		CredentialOrder: config.CredentialOrder,
+		Source:          config.Source,
+		Recorder:        config.Recorder,
+		TrackFreshness:  config.TrackFreshness,
+		StaleThreshold:  config.StaleThreshold,
+		Decoder:         config.Decoder,
+		Capture:         config.Capture,
+		CaptureHeaders:  config.CaptureHeaders,
+		IDOffset:        config.IDOffset,
+		requestURL:      requestURL,
+		credentialURLs:  credentialURLs,
+		retryPolicy:     retryPolicy,
+		throttle:        &throttleGate{},
+		capturer:        newCapturer(config.Capture),
+	}
+
+	if util.Tracer == nil {
+		util.Tracer = noop.NewTracerProvider().Tracer("relay-util")
 	}
 
 	util.GoroutinesConfig = util.getGoroutinesConfig(util.Goroutines, util.Wait)
 
-	return util
+	return util, nil
 }
 
 // SendRelays sends the relays to the Portal API and stores the results in the ResultChan.
 func (u *Util) SendRelays() {
 	var counter atomic.Int32
 	startTime := time.Now() // Capture the start time
+	u.startTime = startTime
 
 	// Create a new progress bar with the total count of relays
-	bar := pb.StartNew(u.Executions)
+	bar := pb.New(u.Executions)
 	blue := color.New(color.FgBlue).SprintFunc()
 
 	// Customize the progress bar template to include the prefix with relay count
 	bar.SetTemplateString(`{{string . "prefix"}} {{bar . "[" "=" ">" "_" "]"}} {{percent .}}`)
 	bar.SetWidth(80)
 	bar.SetMaxWidth(90)
+	if u.Quiet {
+		bar.SetWriter(io.Discard)
+	}
+	bar.Start()
 
 	runInGoroutines(
 		u.GoroutinesConfig,
@@ -119,111 +318,12 @@ func (u *Util) SendRelays() {
 			prefix := fmt.Sprintf("%s 📡 Sending relay %d of %d", blue("EXECUTION"), currentRelay, u.Executions)
 			bar.Set("prefix", prefix).Increment()
 
-			result := RelayResult{
-				ID: currentRelay,
-			}
-
-			startTime := time.Now() // Start time measurement
-
-			if u.IsBatch {
-				responses, err := u.makeJSONRPCBatchReq()       // Make the JSON-RPC request
-				latency := time.Since(startTime).Milliseconds() // Calculate latency
-
-				successfulResponses := []*Response{}
-
-				for _, response := range responses {
-					if err != nil {
-						result.Err = true
-						result.ErrReason = err.Error()
-						u.ResultChan <- result
-						return
-					}
-					if response == nil {
-						result.Err = true
-						result.ErrReason = "response is nil"
-						u.ResultChan <- result
-						return
-					}
-
-					if response.Error.Message != "" {
-						result.Err = true
-						result.ErrReason = fmt.Sprintf("code: %d, message: %s", response.Error.Code, response.Error.Message)
-						u.ResultChan <- result
-						return
-					} else {
-						successfulResponses = append(successfulResponses, response)
-					}
-
-				}
-
-				responseJSON, err := json.Marshal(successfulResponses)
-				if err != nil {
-					result.Err = true
-					result.ErrReason = "failed to marshal response result to JSON"
-					u.ResultChan <- result
-					return
-				}
-
-				if string(responseJSON) == "null" {
-					result.Err = true
-					result.ErrReason = "response body is set to 'null'"
-					u.ResultChan <- result
-					return
-				}
-
-				result.SuccessBody = string(responseJSON)
-				result.Latency = int32(latency) // Store latency in the result
-				u.ResultChan <- result
-				return
-			} else {
-				response, err := u.makeJSONRPCReq()             // Make the JSON-RPC request
-				latency := time.Since(startTime).Milliseconds() // Calculate latency
-
-				if err != nil {
-					result.Err = true
-					result.ErrReason = err.Error()
-					u.ResultChan <- result
-					return
-				}
-				if response == nil {
-					result.Err = true
-					result.ErrReason = "response is nil"
-					u.ResultChan <- result
-					return
-				}
-
-				if response.Error.Message != "" {
-					result.Err = true
-					result.ErrReason = fmt.Sprintf("code: %d, message: %s", response.Error.Code, response.Error.Message)
-					u.ResultChan <- result
-					return
-				} else {
-					responseJSON, err := json.Marshal(response.Result)
-					if err != nil {
-						result.Err = true
-						result.ErrReason = "failed to marshal response result to JSON"
-						u.ResultChan <- result
-						return
-					}
-
-					if string(responseJSON) == "null" {
-						result.Err = true
-						result.ErrReason = "response body is set to 'null'"
-						u.ResultChan <- result
-						return
-					}
-
-					result.SuccessBody = string(responseJSON)
-					result.Latency = int32(latency) // Store latency in the result
-					u.ResultChan <- result
-					return
-				}
-			}
-
+			u.ResultChan <- u.sendRelay(u.IDOffset + currentRelay)
 		},
 	)
 
 	u.ExecTime = time.Since(startTime) // Capture the execution time
+	u.ThrottlePause = u.throttle.paused()
 
 	u.RequestsPerSecond = float64(u.Executions) / u.ExecTime.Seconds()
 
@@ -232,6 +332,233 @@ func (u *Util) SendRelays() {
 	close(u.ResultChan)
 }
 
+// sendRelay sends a single relay inside a client span, retrying it
+// according to the retry policy, and returns its result.
+func (u *Util) sendRelay(id int32) RelayResult {
+	credential := u.credential(id)
+	ctx := context.WithValue(context.Background(), relayIDKey{}, id)
+	ctx = context.WithValue(ctx, credentialKey{}, credential)
+
+	// Send the relay of a Source at its offset from the start of the run
+	if u.Source != nil {
+		request := u.Source.Request(id)
+		ctx = context.WithValue(ctx, requestKey{}, request)
+		if request.At > 0 {
+			time.Sleep(time.Until(u.startTime.Add(request.At)))
+		}
+	}
+
+	ctx, span := u.Tracer.Start(ctx, "relay",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("relay.id", int(id))),
+	)
+	defer span.End()
+
+	result := RelayResult{
+		ID: id,
+	}
+	if credential >= 0 {
+		result.Credential = u.Credentials[credential].Name
+		span.SetAttributes(attribute.String("relay.credential", result.Credential))
+	}
+	if spanContext := span.SpanContext(); spanContext.HasTraceID() {
+		result.TraceID = spanContext.TraceID().String()
+	}
+
+	startTime := time.Now() // Start time measurement
+	result.StartedAt = startTime
+
+	var last attempt
+	for {
+		// In adaptive mode, hold off while the endpoint is throttling
+		if u.Adaptive {
+			u.throttle.wait()
+		}
+
+		result.Attempts++
+		last = u.sendAttempt(ctx)
+
+		if result.Attempts == 1 {
+			result.FirstAttemptLatency = int32(last.latency.Milliseconds())
+		}
+
+		throttled := last.isThrottled()
+		if throttled {
+			result.ThrottledAttempts++
+			if u.Adaptive {
+				u.throttle.throttled(last.retryAfter)
+			}
+		} else if u.Adaptive {
+			u.throttle.ok()
+		}
+
+		if last.err == nil || result.Attempts >= u.retryPolicy.maxAttempts || !u.retryPolicy.isRetryable(last) {
+			break
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("relay.attempt", result.Attempts+1),
+			attribute.String("relay.retry_reason", redact.Text(last.err.Error())),
+		))
+
+		// Honour the endpoint's Retry-After, unless the throttle gate already does
+		delay := u.retryPolicy.backoff(result.Attempts)
+		if throttled && !u.Adaptive {
+			delay = max(delay, last.retryAfter)
+		}
+		time.Sleep(delay)
+	}
+
+	result.Latency = int32(time.Since(startTime).Milliseconds()) // Calculate end-to-end latency
+	result.Timings = last.timings
+	result.Headers = last.headers
+
+	if last.err != nil {
+		result.Err = true
+		result.ErrReason = redact.Text(errorReason(last.err))
+		result.Throttled = last.isThrottled()
+		var rpcErr RelayError
+		if errors.As(last.err, &rpcErr) {
+			result.ErrCode = rpcErr.Code
+		}
+		span.SetStatus(codes.Error, result.ErrReason)
+	} else {
+		result.SuccessBody = last.successBody
+		result.Height, result.HasHeight = last.height, last.hasHeight
+		if u.Decoder == nil && u.TrackFreshness {
+			result.Height, result.HasHeight = BlockHeight(result.SuccessBody)
+		}
+	}
+
+	if u.Recorder != nil || u.capturer != nil {
+		exchange := u.exchange(ctx, result, last, time.Since(startTime))
+		if u.Recorder != nil {
+			u.Recorder.Record(*exchange)
+		}
+		if u.capturer != nil {
+			u.capturer.capture(exchange, result.TraceID)
+		}
+	}
+	return result
+}
+
+// exchange returns the exchange of the last attempt of the relay, to be recorded or captured.
+func (u *Util) exchange(ctx context.Context, result RelayResult, last attempt, latency time.Duration) *Exchange {
+	exchange := last.exchange
+	if exchange == nil {
+		// The request could not be built, so only the relay's request is known
+		exchange = &Exchange{URL: u.URL, RequestBody: u.request(ctx).Body}
+	}
+	exchange.ID = result.ID
+	exchange.StartedAt = result.StartedAt
+	exchange.Latency = latency
+	exchange.Timings = result.Timings
+	exchange.Attempts = result.Attempts
+	exchange.Err = result.ErrReason
+	return exchange
+}
+
+// sendAttempt makes a single attempt at sending the relay.
+func (u *Util) sendAttempt(ctx context.Context) attempt {
+	// Record the HTTP phases of the request
+	ctx, timer := withPhaseTimer(ctx)
+
+	var a attempt
+	if u.Recorder != nil || u.capturer != nil {
+		a.exchange = &Exchange{}
+		ctx = context.WithValue(ctx, exchangeKey{}, a.exchange)
+	}
+	startTime := time.Now()
+
+	body := u.request(ctx).Body
+	if isBatch(body) {
+		responses, httpResp, err := u.makeJSONRPCBatchReq(ctx) // Make the JSON-RPC request
+		a.latency = time.Since(startTime)
+		a.setHTTPResponse(httpResp)
+		a.headers = u.capturedHeaders(httpResp)
+		a.successBody, a.err = batchSuccessBody(responses, err)
+	} else {
+		response, httpResp, err := u.makeJSONRPCReq(ctx) // Make the JSON-RPC request
+		a.latency = time.Since(startTime)
+		a.setHTTPResponse(httpResp)
+		a.headers = u.capturedHeaders(httpResp)
+		a.successBody, a.err = singleSuccessBody(response, err)
+		if a.err == nil && u.Decoder != nil {
+			if a.height, a.hasHeight, a.err = u.Decoder.Decode(body, a.successBody); a.err != nil {
+				a.successBody, a.err = "", fmt.Errorf("invalid result: %w", a.err)
+			}
+		}
+	}
+	a.timings = timer.timings()
+
+	return a
+}
+
+// setHTTPResponse records the status code and requested backoff
+// of the attempt's HTTP response, if one was received.
+func (a *attempt) setHTTPResponse(httpResp *http.Response) {
+	if httpResp != nil {
+		a.statusCode = httpResp.StatusCode
+		a.retryAfter = parseRetryAfter(httpResp.Header, time.Now())
+	}
+}
+
+// singleSuccessBody validates a single JSON-RPC response and returns its result as JSON.
+func singleSuccessBody(response *Response, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	if response == nil {
+		return "", fmt.Errorf("response is nil")
+	}
+	if response.Error.Message != "" {
+		return "", response.Error
+	}
+
+	responseJSON, err := json.Marshal(response.Result)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response result to JSON")
+	}
+	if string(responseJSON) == "null" {
+		return "", fmt.Errorf("response body is set to 'null'")
+	}
+
+	return string(responseJSON), nil
+}
+
+// batchSuccessBody validates a batch of JSON-RPC responses and returns them as JSON.
+func batchSuccessBody(responses []*Response, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	successfulResponses := []*Response{}
+	for _, response := range responses {
+		if response == nil {
+			return "", fmt.Errorf("response is nil")
+		}
+		if response.Error.Message != "" {
+			return "", response.Error
+		}
+		successfulResponses = append(successfulResponses, response)
+	}
+
+	responseJSON, err := json.Marshal(successfulResponses)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response result to JSON")
+	}
+	if string(responseJSON) == "null" {
+		return "", fmt.Errorf("response body is set to 'null'")
+	}
+
+	return string(responseJSON), nil
+}
+
+// Error returns the JSON-RPC error in the format used for error reasons.
+func (e RelayError) Error() string {
+	return fmt.Sprintf("code: %d, message: %s", e.Code, e.Message)
+}
+
 // IDFromString creates an ID from a string.
 func IDFromString(id string) ID {
 	return ID{string: id, isNumber: false}
@@ -274,88 +601,165 @@ func (i ID) String() string {
 	return i.string
 }
 
-// Add the setRequestHeaders method to the Util struct
-func (u *Util) setRequestHeaders(req *http.Request) {
-	// Set headers from the Util struct
+// setRequestHeaders sets the configured headers on the request,
+// injects the W3C trace context of the span carried by ctx
+// and sets the credentials of the auth provider, if any.
+func (u *Util) setRequestHeaders(ctx context.Context, req *http.Request) error {
+	// Set the headers of the relay's own request, if it comes from a Source
+	for key, values := range u.request(ctx).Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// Set headers from the Util struct, overriding those of the Source
 	for key, values := range u.Headers {
+		req.Header.Del(key)
 		for _, value := range values {
 			req.Header.Add(key, value)
 		}
 	}
-}
 
-// makeJSONRPCReq makes a JSON-RPC request to the Portal API.
-func (u *Util) makeJSONRPCReq() (*Response, error) {
-	var req *http.Request
-	var err error
-	if len(u.Body) == 0 {
-		req, err = http.NewRequest(http.MethodGet, u.URL, nil)
-	} else {
-		req, err = http.NewRequest(http.MethodPost, u.URL, bytes.NewBuffer(u.Body))
+	// Propagate the relay span as a traceparent header
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	// Let the local proxy attribute what it does to the relay
+	if id, ok := ctx.Value(relayIDKey{}).(int32); ok && u.Via != "" {
+		req.Header.Set(IDHeader, strconv.Itoa(int(id)))
 	}
-	if err != nil {
-		return nil, err
+
+	// Set the rotated credential of the relay
+	if credential := credentialFromContext(ctx); credential >= 0 && u.Credentials[credential].Header != "" {
+		req.Header.Set(u.Credentials[credential].Header, u.Credentials[credential].Value)
 	}
 
-	// Set headers using the new method
-	u.setRequestHeaders(req)
+	// Compute the credentials of each request, so that they are refreshed during long runs
+	if u.Auth != nil {
+		if err := u.Auth.Authenticate(req, u.request(ctx).Body); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
 
-	httpResp, err := u.HTTPClient.Do(req)
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// errorReason returns the reason of a failed relay. The local and remote addresses of
+// network errors are left out, so that the same failure on different connections is
+// reported as a single reason.
+func errorReason(err error) string {
+	var urlErr *url.Error
+	var opErr *net.OpError
+	if errors.As(err, &urlErr) && errors.As(err, &opErr) {
+		cause := opErr.Err
+		var syscallErr *os.SyscallError
+		if errors.As(cause, &syscallErr) {
+			cause = syscallErr.Err
+		}
+		return fmt.Sprintf("%s %q: %s %s: %v", urlErr.Op, urlErr.URL, opErr.Op, opErr.Net, cause)
 	}
+	return err.Error()
+}
 
-	defer httpResp.Body.Close()
-	body, err := io.ReadAll(httpResp.Body)
+// viaURL returns the URL with the scheme and host of the via URL, keeping its path, query and user info.
+func viaURL(rawURL, via string) (string, error) {
+	target, err := url.Parse(rawURL)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("invalid URL: %w", err)
 	}
+	proxy, err := url.Parse(via)
+	if err != nil {
+		return "", fmt.Errorf("invalid via URL: %w", err)
+	}
+	target.Scheme, target.Host = proxy.Scheme, proxy.Host
+	return target.String(), nil
+}
 
-	fmt.Println(string(body))
+// makeJSONRPCReq makes a JSON-RPC request to the Portal API.
+func (u *Util) makeJSONRPCReq(ctx context.Context) (*Response, *http.Response, error) {
+	httpResp, body, err := u.doRequest(ctx)
+	if err != nil {
+		return nil, httpResp, err
+	}
 
 	var resp Response
 	err = json.Unmarshal(body, &resp)
 	if err != nil {
-		return nil, err
+		return nil, httpResp, err
 	}
 
-	return &resp, nil
+	return &resp, httpResp, nil
 }
 
 // makeJSONRPCBatchReq makes a JSON-RPC request to the Portal API.
-func (u *Util) makeJSONRPCBatchReq() ([]*Response, error) {
+func (u *Util) makeJSONRPCBatchReq(ctx context.Context) ([]*Response, *http.Response, error) {
+	httpResp, body, err := u.doRequest(ctx)
+	if err != nil {
+		return nil, httpResp, err
+	}
+
+	var resp []*Response
+	err = json.Unmarshal(body, &resp)
+	if err != nil {
+		return nil, httpResp, err
+	}
+
+	return resp, httpResp, nil
+}
+
+// doRequest sends the configured request to the Portal API and returns the
+// HTTP response along with its body, which has already been read and closed.
+func (u *Util) doRequest(ctx context.Context) (*http.Response, []byte, error) {
+	requestURL := u.requestURL
+	if credential := credentialFromContext(ctx); credential >= 0 {
+		requestURL = u.credentialURLs[credential]
+	}
+
+	body := u.request(ctx).Body
+
 	var req *http.Request
 	var err error
-	if len(u.Body) == 0 {
-		req, err = http.NewRequest(http.MethodGet, u.URL, nil)
+	if len(body) == 0 {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	} else {
-		req, err = http.NewRequest(http.MethodPost, u.URL, bytes.NewBuffer(u.Body))
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewBuffer(body))
 	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Set headers using the new method
-	u.setRequestHeaders(req)
+	if err := u.setRequestHeaders(ctx, req); err != nil {
+		return nil, nil, err
+	}
+
+	// Record the request as sent to the URL, rather than to a local proxy
+	exchange := exchangeFromContext(ctx)
+	if exchange != nil {
+		exchange.Method = req.Method
+		exchange.URL = strings.Replace(requestURL, u.requestURL, u.URL, 1)
+		exchange.RequestHeaders = req.Header.Clone()
+		exchange.RequestBody = body
+	}
 
 	httpResp, err := u.HTTPClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPResponseStatusCode(httpResp.StatusCode))
 
 	defer httpResp.Body.Close()
-	body, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return nil, err
+	respBody, err := io.ReadAll(httpResp.Body)
+	if exchange != nil {
+		exchange.Status = httpResp.StatusCode
+		exchange.ResponseHeaders = httpResp.Header.Clone()
+		exchange.ResponseBody = respBody
 	}
-
-	var resp []*Response
-	err = json.Unmarshal(body, &resp)
 	if err != nil {
-		return nil, err
+		return httpResp, nil, err
 	}
+	markBodyRead(ctx)
 
-	return resp, nil
+	return httpResp, respBody, nil
 }
 
 // getGoroutinesConfig returns the goroutines config based on the plan type.
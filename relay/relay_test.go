@@ -0,0 +1,290 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/mock"
+)
+
+const (
+	blockNumberBody = `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`
+	batchBody       = `[{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]},` +
+		`{"jsonrpc":"2.0","id":2,"method":"eth_chainId","params":[]},` +
+		`{"jsonrpc":"2.0","id":3,"method":"net_version","params":[]}]`
+)
+
+// newMock serves a mock server with the config for the duration of the test.
+func newMock(t *testing.T, config mock.Config) (*mock.Server, *httptest.Server) {
+	t.Helper()
+	if config.Seed == 0 {
+		config.Seed = 1
+	}
+	server, err := mock.New(config)
+	if err != nil {
+		t.Fatalf("mock.New() error = %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	return server, httpServer
+}
+
+// sequence serves the first n requests with the first handler and the rest with the second,
+// so that a relay fails a set number of times before it succeeds.
+func sequence(t *testing.T, first http.Handler, n int64, then http.Handler) *httptest.Server {
+	t.Helper()
+	var requests atomic.Int64
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= n {
+			first.ServeHTTP(w, r)
+			return
+		}
+		then.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+// newServer returns a mock server with the config, without serving it.
+func newServer(t *testing.T, config mock.Config) *mock.Server {
+	t.Helper()
+	config.Seed = 1
+	server, err := mock.New(config)
+	if err != nil {
+		t.Fatalf("mock.New() error = %v", err)
+	}
+	return server
+}
+
+// send sends the relays of the config quietly and returns their results.
+func send(t *testing.T, config Config) (*Util, []RelayResult) {
+	t.Helper()
+	config.Quiet = true
+	if config.Executions == 0 {
+		config.Executions = 1
+	}
+	if config.Goroutines == 0 {
+		config.Goroutines = 1
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 5 * time.Second
+	}
+	u, err := NewRelayUtil(config)
+	if err != nil {
+		t.Fatalf("NewRelayUtil() error = %v", err)
+	}
+	u.SendRelays()
+	return u, u.CollectResults()
+}
+
+func TestSingleRelays(t *testing.T) {
+	server, httpServer := newMock(t, mock.Config{StartBlock: 100})
+
+	_, results := send(t, Config{URL: httpServer.URL, Body: []byte(blockNumberBody), Executions: 10, Goroutines: 3})
+
+	if len(results) != 10 {
+		t.Fatalf("got %d results, want 10", len(results))
+	}
+	ids := make(map[int32]bool)
+	for _, result := range results {
+		if result.Err {
+			t.Errorf("relay %d failed: %s", result.ID, result.ErrReason)
+		}
+		if result.SuccessBody != `"0x64"` {
+			t.Errorf("relay %d success body = %s, want \"0x64\"", result.ID, result.SuccessBody)
+		}
+		if result.Attempts != 1 {
+			t.Errorf("relay %d attempts = %d, want 1", result.ID, result.Attempts)
+		}
+		ids[result.ID] = true
+	}
+	if len(ids) != 10 {
+		t.Errorf("got %d distinct relay IDs, want 10", len(ids))
+	}
+	if calls := server.Stats().Calls; calls != 10 {
+		t.Errorf("mock served %d calls, want 10", calls)
+	}
+}
+
+func TestBatchRelays(t *testing.T) {
+	server, httpServer := newMock(t, mock.Config{StartBlock: 100})
+
+	u, results := send(t, Config{URL: httpServer.URL, Body: []byte(batchBody), Executions: 4})
+
+	if !u.IsBatch {
+		t.Error("IsBatch = false, want true")
+	}
+	for _, result := range results {
+		if result.Err {
+			t.Fatalf("relay %d failed: %s", result.ID, result.ErrReason)
+		}
+		var responses []Response
+		if err := json.Unmarshal([]byte(result.SuccessBody), &responses); err != nil {
+			t.Fatalf("success body is not a batch: %v", err)
+		}
+		if len(responses) != 3 {
+			t.Errorf("got %d responses, want 3", len(responses))
+		}
+	}
+	if calls := server.Stats().Calls; calls != 12 {
+		t.Errorf("mock served %d calls, want 12", calls)
+	}
+}
+
+func TestJSONRPCErrorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "single", body: blockNumberBody},
+		{name: "batch", body: batchBody},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, httpServer := newMock(t, mock.Config{ErrorRate: 100, ErrorCodes: []int{-32602}})
+
+			u, results := send(t, Config{URL: httpServer.URL, Body: []byte(test.body), Executions: 3})
+
+			for _, result := range results {
+				if !result.Err {
+					t.Fatalf("relay %d succeeded, want a JSON-RPC error", result.ID)
+				}
+				if result.ErrCode != -32602 {
+					t.Errorf("relay %d error code = %d, want -32602", result.ID, result.ErrCode)
+				}
+				if !strings.Contains(result.ErrReason, "invalid params") {
+					t.Errorf("relay %d error reason = %q, want the message of the error", result.ID, result.ErrReason)
+				}
+			}
+			if codes := u.Stats().ErrorCodes; codes[-32602] != 3 {
+				t.Errorf("error codes = %v, want 3 relays with -32602", codes)
+			}
+		})
+	}
+}
+
+func TestRetries5xxWithBackoff(t *testing.T) {
+	unavailable := newServer(t, mock.Config{HTTP5xxRate: 100})
+	healthy := newServer(t, mock.Config{})
+	retry := RetryConfig{MaxAttempts: 3, Backoff: 50 * time.Millisecond, MaxBackoff: time.Second}
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		httpServer := sequence(t, unavailable, 2, healthy)
+
+		_, results := send(t, Config{URL: httpServer.URL, Body: []byte(blockNumberBody), Retry: retry})
+
+		result := results[0]
+		if result.Err {
+			t.Fatalf("relay failed: %s", result.ErrReason)
+		}
+		if result.Attempts != 3 {
+			t.Errorf("attempts = %d, want 3", result.Attempts)
+		}
+		// The backoffs of 50ms and 100ms are each randomized down to no less than half
+		if latency := time.Duration(result.Latency) * time.Millisecond; latency < 75*time.Millisecond {
+			t.Errorf("latency = %s, want at least the 75ms of backoff", latency)
+		}
+	})
+
+	t.Run("fails once attempts run out", func(t *testing.T) {
+		httpServer := sequence(t, unavailable, 3, healthy)
+
+		_, results := send(t, Config{URL: httpServer.URL, Body: []byte(blockNumberBody), Retry: retry})
+
+		if result := results[0]; !result.Err || result.Attempts != 3 {
+			t.Errorf("err = %t, attempts = %d, want a failure after 3 attempts", result.Err, result.Attempts)
+		}
+	})
+
+	t.Run("is not retried unless configured", func(t *testing.T) {
+		httpServer := sequence(t, unavailable, 1, healthy)
+		noRetry := retry
+		noRetry.RetryOn = []string{"timeout"}
+
+		_, results := send(t, Config{URL: httpServer.URL, Body: []byte(blockNumberBody), Retry: noRetry})
+
+		if result := results[0]; !result.Err || result.Attempts != 1 {
+			t.Errorf("err = %t, attempts = %d, want a failure after 1 attempt", result.Err, result.Attempts)
+		}
+	})
+}
+
+func TestRetryAfter429(t *testing.T) {
+	for _, adaptive := range []bool{false, true} {
+		name := "plain"
+		if adaptive {
+			name = "adaptive"
+		}
+		t.Run(name, func(t *testing.T) {
+			throttling := newServer(t, mock.Config{HTTP429Rate: 100, RetryAfter: time.Second})
+			httpServer := sequence(t, throttling, 1, newServer(t, mock.Config{}))
+
+			u, results := send(t, Config{
+				URL:      httpServer.URL,
+				Body:     []byte(blockNumberBody),
+				Retry:    RetryConfig{MaxAttempts: 2, Backoff: time.Millisecond},
+				Adaptive: adaptive,
+			})
+
+			result := results[0]
+			if result.Err {
+				t.Fatalf("relay failed: %s", result.ErrReason)
+			}
+			if result.ThrottledAttempts != 1 {
+				t.Errorf("throttled attempts = %d, want 1", result.ThrottledAttempts)
+			}
+			// Both modes wait for the Retry-After of the endpoint rather than the 1ms backoff
+			if latency := time.Duration(result.Latency) * time.Millisecond; latency < 900*time.Millisecond {
+				t.Errorf("latency = %s, want the relay to wait for Retry-After", latency)
+			}
+			if paused := u.ThrottlePause > 0; paused != adaptive {
+				t.Errorf("throttle pause = %s, want a pause only in adaptive mode", u.ThrottlePause)
+			}
+		})
+	}
+}
+
+func TestConnectionResets(t *testing.T) {
+	_, httpServer := newMock(t, mock.Config{ResetRate: 100})
+
+	_, results := send(t, Config{URL: httpServer.URL, Body: []byte(blockNumberBody), Executions: 2})
+
+	for _, result := range results {
+		if !result.Err {
+			t.Fatalf("relay %d succeeded, want a connection reset", result.ID)
+		}
+		if result.ErrReason == "" {
+			t.Errorf("relay %d has no error reason", result.ID)
+		}
+		if result.Latency >= 1000 {
+			t.Errorf("relay %d took %dms, want the reset to fail it at once", result.ID, result.Latency)
+		}
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	_, httpServer := newMock(t, mock.Config{Latency: mock.Latency{Distribution: mock.DistributionFixed, Mean: 500 * time.Millisecond}})
+
+	_, results := send(t, Config{
+		URL:     httpServer.URL,
+		Body:    []byte(blockNumberBody),
+		Timeout: 50 * time.Millisecond,
+		Retry:   RetryConfig{MaxAttempts: 2, RetryOn: []string{"timeout"}},
+	})
+
+	result := results[0]
+	if !result.Err {
+		t.Fatal("relay succeeded, want a timeout")
+	}
+	if result.Attempts != 2 {
+		t.Errorf("attempts = %d, want the timeout to be retried once", result.Attempts)
+	}
+	if !strings.Contains(strings.ToLower(result.ErrReason), "timeout") && !strings.Contains(result.ErrReason, "deadline exceeded") {
+		t.Errorf("error reason = %q, want a timeout", result.ErrReason)
+	}
+}
@@ -0,0 +1,148 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRetryOn is the list of retryable failures used when none are configured.
+var DefaultRetryOn = []string{"timeout", "429", "5xx"}
+
+type (
+	// RetryConfig configures how failed relays are retried.
+	// A MaxAttempts of 0 or 1 disables retries.
+	//
+	// RetryOn lists the failures that are retried:
+	//   - "timeout" for request timeouts
+	//   - "5xx" for any HTTP 5xx status code
+	//   - an HTTP status code, eg. "429" or "503"
+	//   - a JSON-RPC error code, eg. "-32000" or "rpc:429"
+	RetryConfig struct {
+		MaxAttempts int
+		Backoff     time.Duration
+		MaxBackoff  time.Duration
+		RetryOn     []string
+	}
+
+	// retryPolicy is the parsed form of a RetryConfig.
+	retryPolicy struct {
+		maxAttempts  int
+		backoffBase  time.Duration
+		backoffMax   time.Duration
+		timeouts     bool
+		serverErrors bool
+		statusCodes  map[int]bool
+		rpcCodes     map[int]bool
+	}
+)
+
+// Enabled returns true if failed relays may be attempted more than once.
+func (c RetryConfig) Enabled() bool {
+	return c.MaxAttempts > 1
+}
+
+// newRetryPolicy parses and validates the retry config.
+func newRetryPolicy(config RetryConfig) (retryPolicy, error) {
+	policy := retryPolicy{
+		maxAttempts: max(config.MaxAttempts, 1),
+		backoffBase: config.Backoff,
+		backoffMax:  config.MaxBackoff,
+		statusCodes: make(map[int]bool),
+		rpcCodes:    make(map[int]bool),
+	}
+
+	if config.Backoff < 0 || config.MaxBackoff < 0 {
+		return policy, fmt.Errorf("retry backoff must be greater than or equal to 0")
+	}
+	if policy.backoffMax == 0 || policy.backoffMax < policy.backoffBase {
+		policy.backoffMax = policy.backoffBase
+	}
+
+	retryOn := config.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryOn
+	}
+
+	for _, condition := range retryOn {
+		condition = strings.ToLower(strings.TrimSpace(condition))
+		switch {
+		case condition == "timeout":
+			policy.timeouts = true
+		case condition == "5xx":
+			policy.serverErrors = true
+		case strings.HasPrefix(condition, "rpc:"):
+			code, err := strconv.Atoi(strings.TrimPrefix(condition, "rpc:"))
+			if err != nil {
+				return policy, fmt.Errorf("invalid JSON-RPC code in retry condition %q", condition)
+			}
+			policy.rpcCodes[code] = true
+		default:
+			code, err := strconv.Atoi(condition)
+			switch {
+			case err != nil:
+				return policy, fmt.Errorf("invalid retry condition %q, must be timeout, 5xx, an HTTP status code or a JSON-RPC code", condition)
+			case code < 0:
+				policy.rpcCodes[code] = true
+			case code >= 100 && code <= 599:
+				policy.statusCodes[code] = true
+			default:
+				return policy, fmt.Errorf("invalid HTTP status code in retry condition %q", condition)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// isRetryable returns true if the failed attempt matches one of the retry conditions.
+func (p retryPolicy) isRetryable(a attempt) bool {
+	if a.err == nil {
+		return false
+	}
+
+	if p.timeouts && isTimeout(a.err) {
+		return true
+	}
+	if p.statusCodes[a.statusCode] || (p.serverErrors && a.statusCode >= 500 && a.statusCode <= 599) {
+		return true
+	}
+
+	var rpcErr RelayError
+	if errors.As(a.err, &rpcErr) && p.rpcCodes[rpcErr.Code] {
+		return true
+	}
+
+	return false
+}
+
+// backoff returns the delay before the next attempt, after the given number of attempts.
+// The delay grows exponentially up to the max backoff, with up to half of it randomized.
+func (p retryPolicy) backoff(attempts int) time.Duration {
+	if p.backoffBase <= 0 {
+		return 0
+	}
+
+	delay := p.backoffBase
+	for i := 1; i < attempts && delay < p.backoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.backoffMax)
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)) // #nosec G404 -- jitter does not need a secure source
+}
+
+// isTimeout returns true if the error was caused by a timeout.
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,79 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// Source provides the request of each relay instead of the configured body,
+	// such as recorded or imported traffic.
+	Source interface {
+		// Request returns the request of the relay with the ID, starting at 1.
+		Request(id int32) Request
+	}
+
+	// Request is the request of a single relay provided by a Source.
+	Request struct {
+		Body []byte
+		// Headers are set on the request, unless the configured headers override them.
+		Headers http.Header
+		// At is the offset from the start of the run at which the relay is sent.
+		// The relay is sent as soon as a goroutine is free if it is zero.
+		At time.Duration
+	}
+
+	// Recorder records the request and response of each relay.
+	// Record is called from the goroutines sending relays.
+	Recorder interface {
+		Record(exchange Exchange)
+	}
+
+	// Exchange is the request and response of the last attempt of a relay.
+	Exchange struct {
+		ID        int32
+		StartedAt time.Time
+		Method    string
+		URL       string
+		// RequestHeaders include the credentials of the request, which the recorder must redact.
+		RequestHeaders  http.Header
+		RequestBody     []byte
+		Status          int
+		ResponseHeaders http.Header
+		ResponseBody    []byte
+		// Latency is the end-to-end latency of the relay, including its retries.
+		Latency  time.Duration
+		Timings  PhaseTimings
+		Attempts int
+		// Err is the error reason of the relay, if it failed.
+		Err string
+	}
+
+	// requestKey is the context key of the request of the relay being sent, if it comes from a Source.
+	requestKey struct{}
+
+	// exchangeKey is the context key of the exchange of the attempt being sent, if relays are recorded or captured.
+	exchangeKey struct{}
+)
+
+// isBatch returns true if the body is a batch of JSON-RPC requests.
+func isBatch(body []byte) bool {
+	return json.Valid(body) && strings.HasPrefix(strings.TrimSpace(string(body)), "[")
+}
+
+// request returns the request of the relay being sent: the one from the Source, or the configured body.
+func (u *Util) request(ctx context.Context) Request {
+	if request, ok := ctx.Value(requestKey{}).(Request); ok {
+		return request
+	}
+	return Request{Body: u.Body}
+}
+
+// exchangeFromContext returns the exchange of the attempt being sent, or nil if relays are neither recorded nor captured.
+func exchangeFromContext(ctx context.Context) *Exchange {
+	exchange, _ := ctx.Value(exchangeKey{}).(*Exchange)
+	return exchange
+}
@@ -0,0 +1,116 @@
+package relay
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stats summarizes the outcome and latency of the relays of a run.
+// Latency statistics only include successful relays. Durations are
+// encoded in JSON as nanoseconds.
+type Stats struct {
+	TotalRelays      int           `json:"total_relays"`
+	SuccessfulRelays int           `json:"successful_relays"`
+	FailedRelays     int           `json:"failed_relays"`
+	SuccessRate      float64       `json:"success_rate"`
+	FailureRate      float64       `json:"failure_rate"`
+	ExecTime         time.Duration `json:"exec_time"`
+	RPS              float64       `json:"rps"`
+	AverageLatency   time.Duration `json:"average_latency"`
+	LowestLatency    time.Duration `json:"lowest_latency"`
+	HighestLatency   time.Duration `json:"highest_latency"`
+	P50Latency       time.Duration `json:"p50_latency"`
+	P90Latency       time.Duration `json:"p90_latency"`
+	P99Latency       time.Duration `json:"p99_latency"`
+	// ErrorCodes counts the failed relays by JSON-RPC error code.
+	ErrorCodes map[int]int `json:"error_codes"`
+}
+
+// CollectResults drains the ResultChan once SendRelays has returned and
+// returns all relay results. It may be called any number of times.
+func (u *Util) CollectResults() []RelayResult {
+	for result := range u.ResultChan {
+		u.Results = append(u.Results, result)
+	}
+	return u.Results
+}
+
+// SetResults sets the results of relays sent by other processes, such as distributed
+// agents, as the results of the run, in place of SendRelays.
+func (u *Util) SetResults(results []RelayResult, execTime time.Duration) {
+	u.Results = results
+	u.ExecTime = execTime
+	if execTime > 0 {
+		u.RequestsPerSecond = float64(len(results)) / execTime.Seconds()
+	}
+	close(u.ResultChan)
+}
+
+// Stats returns the statistics of the relays sent by SendRelays.
+func (u *Util) Stats() Stats {
+	return NewStats(u.CollectResults(), u.ExecTime)
+}
+
+// NewStats calculates the statistics of a set of relay results.
+func NewStats(results []RelayResult, execTime time.Duration) Stats {
+	stats := Stats{
+		TotalRelays: len(results),
+		ExecTime:    execTime,
+		ErrorCodes:  make(map[int]int),
+	}
+
+	var latencies []time.Duration
+	for _, result := range results {
+		if result.Err {
+			stats.FailedRelays++
+			if result.ErrCode != 0 {
+				stats.ErrorCodes[result.ErrCode]++
+			}
+			continue
+		}
+		stats.SuccessfulRelays++
+		if result.Latency != 0 {
+			latencies = append(latencies, time.Duration(result.Latency)*time.Millisecond)
+		}
+	}
+
+	if stats.TotalRelays > 0 {
+		stats.SuccessRate = float64(stats.SuccessfulRelays) / float64(stats.TotalRelays) * 100
+		stats.FailureRate = float64(stats.FailedRelays) / float64(stats.TotalRelays) * 100
+	}
+	if execTime > 0 {
+		stats.RPS = float64(stats.TotalRelays) / execTime.Seconds()
+	}
+
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var totalLatency time.Duration
+	for _, latency := range latencies {
+		totalLatency += latency
+	}
+	stats.AverageLatency = totalLatency / time.Duration(len(latencies))
+	stats.LowestLatency = latencies[0]
+	stats.HighestLatency = latencies[len(latencies)-1]
+	stats.P50Latency = Percentile(latencies, 0.5)
+	stats.P90Latency = Percentile(latencies, 0.9)
+	stats.P99Latency = Percentile(latencies, 0.99)
+
+	return stats
+}
+
+// Percentile returns the nearest-rank percentile p of the sorted durations.
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(float64(len(sorted))*p)) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
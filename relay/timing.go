@@ -0,0 +1,98 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type (
+	// PhaseTimings holds the duration of each phase of a relay's HTTP request.
+	// Phases that did not happen, such as DNS lookup on a reused connection, are zero.
+	PhaseTimings struct {
+		DNS     time.Duration `json:"dns"`
+		Connect time.Duration `json:"connect"`
+		TLS     time.Duration `json:"tls"`
+		TTFB    time.Duration `json:"ttfb"`
+		Body    time.Duration `json:"body"`
+	}
+
+	// phaseTimer records the phase timestamps reported by httptrace.
+	// Its callbacks may be invoked from different goroutines.
+	phaseTimer struct {
+		mu sync.Mutex
+
+		dnsStart, dnsDone         time.Time
+		connectStart, connectDone time.Time
+		tlsStart, tlsDone         time.Time
+		wroteRequest, firstByte   time.Time
+		bodyDone                  time.Time
+	}
+
+	phaseTimerKey struct{}
+)
+
+// withPhaseTimer returns a context that records the request phases into a new phaseTimer.
+func withPhaseTimer(ctx context.Context) (context.Context, *phaseTimer) {
+	t := &phaseTimer{}
+
+	ctx = context.WithValue(ctx, phaseTimerKey{}, t)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart:         func(_, _ string) { t.markOnce(&t.connectStart) },
+		ConnectDone:          func(_, _ string, _ error) { t.mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	})
+
+	return ctx, t
+}
+
+// markBodyRead records the end of the body download for the request in ctx, if it is being timed.
+func markBodyRead(ctx context.Context) {
+	if t, ok := ctx.Value(phaseTimerKey{}).(*phaseTimer); ok {
+		t.mark(&t.bodyDone)
+	}
+}
+
+func (t *phaseTimer) mark(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*field = time.Now()
+}
+
+// markOnce only records the first call, as dialers may try several addresses in parallel.
+func (t *phaseTimer) markOnce(field *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if field.IsZero() {
+		*field = time.Now()
+	}
+}
+
+// timings returns the duration of each recorded phase.
+func (t *phaseTimer) timings() PhaseTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return PhaseTimings{
+		DNS:     between(t.dnsStart, t.dnsDone),
+		Connect: between(t.connectStart, t.connectDone),
+		TLS:     between(t.tlsStart, t.tlsDone),
+		TTFB:    between(t.wroteRequest, t.firstByte),
+		Body:    between(t.firstByte, t.bodyDone),
+	}
+}
+
+// between returns the duration between two timestamps, or zero if either is missing.
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
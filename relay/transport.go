@@ -0,0 +1,161 @@
+package relay
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// Supported values for TransportConfig.HTTPVersion.
+const (
+	HTTPVersionAuto = ""
+	HTTPVersion1    = "1.1"
+	HTTPVersion2    = "2"
+	HTTPVersionH2C  = "h2c"
+)
+
+// TransportConfig configures the HTTP transport used to send relays.
+// The zero value uses the Go default transport settings, except that
+// the idle connection pool is sized to the number of goroutines.
+type TransportConfig struct {
+	MaxConnsPerHost    int
+	MaxIdleConns       int
+	DisableKeepAlive   bool
+	HTTPVersion        string
+	ProxyURL           string
+	CACertFile         string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+}
+
+// NewHTTPClient creates the HTTP client used to send relays from the transport config.
+func NewHTTPClient(config TransportConfig, timeout time.Duration, goroutines int) (*http.Client, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// HTTP/2 is forced by using the HTTP/2 transport directly, which
+	// fails instead of falling back to HTTP/1.1 if the server does not support it.
+	switch config.HTTPVersion {
+	case HTTPVersion2:
+		return &http.Client{
+			Timeout:   timeout,
+			Transport: &http2.Transport{TLSClientConfig: tlsConfig},
+		}, nil
+
+	case HTTPVersionH2C:
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		return &http.Client{
+			Timeout: timeout,
+			Transport: &http2.Transport{
+				AllowHTTP: true,
+				DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, addr)
+				},
+			},
+		}, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxConnsPerHost = config.MaxConnsPerHost
+	transport.DisableKeepAlives = config.DisableKeepAlive
+
+	// The default of 2 idle connections per host churns connections at any real concurrency
+	idleConns := config.MaxIdleConns
+	if idleConns == 0 {
+		idleConns = goroutines
+	}
+	transport.MaxIdleConns = max(transport.MaxIdleConns, idleConns)
+	transport.MaxIdleConnsPerHost = idleConns
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.HTTPVersion == HTTPVersion1 {
+		// A non-nil empty map disables the HTTP/2 upgrade
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// validate checks the transport config for invalid or unsupported combinations.
+func (c TransportConfig) validate() error {
+	switch c.HTTPVersion {
+	case HTTPVersionAuto, HTTPVersion1:
+	case HTTPVersion2, HTTPVersionH2C:
+		if c.ProxyURL != "" {
+			return fmt.Errorf("a proxy cannot be used with HTTP version %s", c.HTTPVersion)
+		}
+		if c.DisableKeepAlive {
+			return fmt.Errorf("keep-alive cannot be disabled with HTTP version %s", c.HTTPVersion)
+		}
+	default:
+		return fmt.Errorf("unsupported HTTP version %q, must be one of 1.1, 2 or h2c", c.HTTPVersion)
+	}
+
+	if c.MaxConnsPerHost < 0 {
+		return fmt.Errorf("max connections per host must be greater than or equal to 0")
+	}
+	if c.MaxIdleConns < 0 {
+		return fmt.Errorf("max idle connections must be greater than or equal to 0")
+	}
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return fmt.Errorf("client certificate and key must be set together")
+	}
+
+	return nil
+}
+
+// tlsConfig builds the TLS config from the CA bundle, client certificate and verification options.
+func (c TransportConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify, // #nosec G402 -- opt-in for testing against self-signed endpoints
+	}
+
+	if c.CACertFile != "" {
+		pem, err := os.ReadFile(c.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
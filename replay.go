@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/log"
+	"github.com/commoddity/relay-util/v2/record"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/report"
+	"github.com/spf13/pflag"
+)
+
+// replayCommand replays recorded relays against a URL and compares the responses with the recorded ones.
+func replayCommand(args []string) {
+	var (
+		help                         bool
+		url                          string
+		headers                      []string
+		speed                        float64
+		goroutines, timeout          int
+		savePath, htmlPath, recordTo string
+	)
+
+	flagSet := pflag.NewFlagSet("replay", pflag.ExitOnError)
+	flagSet.BoolVarP(&help, "help", "h", false, "Display help information")
+	flagSet.StringVarP(&url, "url", "u", "", "[REQUIRED] The URL to replay the relays against.")
+	flagSet.StringSliceVarP(&headers, "headers", "H", nil, "[OPTIONAL] Headers to add to every replayed relay, such as credentials, which are not recorded. Can be used multiple times.")
+	flagSet.Float64Var(&speed, "speed", 1, "[OPTIONAL] How many times faster than recorded the relays are replayed, eg. 2 or 0.5. 0 replays them as fast as possible.")
+	flagSet.IntVarP(&goroutines, "goroutines", "g", 100, "[OPTIONAL] The maximum number of relays in flight. Relays are sent late if more are due at once.")
+	flagSet.IntVarP(&timeout, "timeout", "t", 20, "[OPTIONAL] The timeout of each relay, measured in seconds.")
+	flagSet.StringVar(&savePath, "save", "", "[OPTIONAL] A JSON file to save the report of the replay to.")
+	flagSet.StringVar(&htmlPath, "html", "", "[OPTIONAL] A self-contained HTML file to write the report of the replay to.")
+	flagSet.StringVar(&recordTo, "record", "", "[OPTIONAL] A file to record the replayed relays to, as HAR or NDJSON.")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s replay [flags] <recording>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Replays the relays of a recording made with --record against a URL, with their original timing,\n")
+		fmt.Fprintf(os.Stderr, "and compares the responses with the recorded ones.\n\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		flagSet.PrintDefaults()
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if help {
+		flagSet.Usage()
+		return
+	}
+
+	if flagSet.NArg() != 1 {
+		exitWithUsageError(fmt.Errorf("replay requires a recording"))
+	}
+	if url == "" {
+		exitWithUsageError(fmt.Errorf("missing required flag: -u, --url for URL"))
+	}
+	if speed < 0 {
+		exitWithUsageError(fmt.Errorf("speed must be greater than or equal to 0"))
+	}
+	headerMap, err := parseHeaders(headers)
+	if err != nil {
+		exitWithUsageError(err)
+	}
+
+	entries, err := record.Load(flagSet.Arg(0))
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	entries, skipped := record.Replayable(entries)
+	if skipped > 0 {
+		log.LogRedactedSkipped(skipped)
+	}
+	if len(entries) == 0 {
+		exitWithUsageError(fmt.Errorf("every request body of %s was changed by redaction, record with --record-unredacted to replay them", flagSet.Arg(0)))
+	}
+
+	/* Relay Util Init */
+	recorder := record.New(record.Config{})
+	relayUtil, err := relay.NewRelayUtil(relay.Config{
+		URL:        url,
+		Headers:    headerMap,
+		Executions: len(entries),
+		Goroutines: goroutines,
+		Timeout:    time.Duration(timeout) * time.Second,
+		Source:     record.NewSource(entries, speed),
+		Recorder:   recorder,
+	})
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	/* Replay Relays */
+	log.PrintConfig(relayUtil)
+	relayUtil.SendRelays()
+	log.LogResults(relayUtil)
+
+	comparison := record.Compare(entries, recorder.Entries())
+	log.LogReplay(comparison)
+
+	if savePath != "" || htmlPath != "" {
+		replayReport := report.New(relayUtil)
+		replayReport.Replay = &comparison
+		writeReports(replayReport, savePath, htmlPath)
+	}
+	saveRecording(recorder, recordTo)
+}
@@ -0,0 +1,169 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Tolerances are the regressions allowed when comparing a run against a baseline,
+	// in their textual form as given on the command line. Empty fields are not checked.
+	Tolerances struct {
+		// MaxSuccessRateDrop is measured in percentage points, eg. 1%.
+		MaxSuccessRateDrop string
+		// MaxRPSDrop is measured relative to the baseline RPS, eg. 10%.
+		MaxRPSDrop string
+		// MaxLatencyIncrease is measured relative to each baseline latency, eg. 20%.
+		MaxLatencyIncrease string
+	}
+
+	// Comparison is the outcome of comparing a run against a baseline.
+	Comparison struct {
+		Deltas []Delta
+		// Checked is true if at least one tolerance was given.
+		Checked bool
+	}
+
+	// Delta is the change of a single metric between the baseline and the current run.
+	Delta struct {
+		Name     string
+		Baseline string
+		Current  string
+		// Change is the change relative to the baseline, or in percentage points for rates.
+		Change string
+		// Regression is true if the metric got worse.
+		Regression bool
+		// Limit is the tolerance of the metric, if any.
+		Limit string
+		// Failed is true if the regression exceeds the tolerance of the metric.
+		Failed bool
+	}
+)
+
+// Passed returns true if no metric exceeded its tolerance.
+func (c Comparison) Passed() bool {
+	for _, delta := range c.Deltas {
+		if delta.Failed {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare compares the current report against the baseline report.
+func Compare(baseline, current *Report, tolerances Tolerances) (Comparison, error) {
+	maxSuccessRateDrop, err := parseTolerance(tolerances.MaxSuccessRateDrop)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("max success rate drop: %w", err)
+	}
+	maxRPSDrop, err := parseTolerance(tolerances.MaxRPSDrop)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("max RPS drop: %w", err)
+	}
+	maxLatencyIncrease, err := parseTolerance(tolerances.MaxLatencyIncrease)
+	if err != nil {
+		return Comparison{}, fmt.Errorf("max latency increase: %w", err)
+	}
+
+	comparison := Comparison{
+		Checked: maxSuccessRateDrop != nil || maxRPSDrop != nil || maxLatencyIncrease != nil,
+	}
+
+	// Success rate changes are measured in percentage points
+	b, c := baseline.Stats.SuccessRate, current.Stats.SuccessRate
+	successRate := Delta{
+		Name:       "Success rate",
+		Baseline:   fmt.Sprintf("%.2f%%", b),
+		Current:    fmt.Sprintf("%.2f%%", c),
+		Change:     fmt.Sprintf("%+.2fpp", c-b),
+		Regression: c < b,
+	}
+	if maxSuccessRateDrop != nil {
+		successRate.Limit = fmt.Sprintf("-%.2fpp", *maxSuccessRateDrop)
+		successRate.Failed = b-c > *maxSuccessRateDrop
+	}
+	comparison.Deltas = append(comparison.Deltas, successRate)
+
+	b, c = baseline.Stats.RPS, current.Stats.RPS
+	rps := Delta{
+		Name:       "RPS",
+		Baseline:   fmt.Sprintf("%.2f", b),
+		Current:    fmt.Sprintf("%.2f", c),
+		Change:     formatChange(b, c),
+		Regression: c < b,
+	}
+	if maxRPSDrop != nil {
+		rps.Limit = fmt.Sprintf("-%.2f%%", *maxRPSDrop)
+		rps.Failed = relativeChange(b, c) < -*maxRPSDrop
+	}
+	comparison.Deltas = append(comparison.Deltas, rps)
+
+	latencies := []struct {
+		name              string
+		baseline, current time.Duration
+	}{
+		{"P50 latency", baseline.Stats.P50Latency, current.Stats.P50Latency},
+		{"P90 latency", baseline.Stats.P90Latency, current.Stats.P90Latency},
+		{"P99 latency", baseline.Stats.P99Latency, current.Stats.P99Latency},
+		{"Average latency", baseline.Stats.AverageLatency, current.Stats.AverageLatency},
+	}
+	for _, latency := range latencies {
+		b, c := float64(latency.baseline), float64(latency.current)
+		delta := Delta{
+			Name:       latency.name,
+			Baseline:   formatLatency(latency.baseline),
+			Current:    formatLatency(latency.current),
+			Change:     formatChange(b, c),
+			Regression: c > b,
+		}
+		if maxLatencyIncrease != nil {
+			delta.Limit = fmt.Sprintf("+%.2f%%", *maxLatencyIncrease)
+			delta.Failed = relativeChange(b, c) > *maxLatencyIncrease
+		}
+		comparison.Deltas = append(comparison.Deltas, delta)
+	}
+
+	return comparison, nil
+}
+
+// parseTolerance parses a tolerance such as "10%" or "10". An empty value is not checked.
+func parseTolerance(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent < 0 {
+		return nil, fmt.Errorf("invalid tolerance %q, must be a percentage greater than or equal to 0%%", value)
+	}
+	return &percent, nil
+}
+
+// relativeChange returns the change from the baseline to the current value, in percent.
+// Any increase from a baseline of zero counts as an infinite change.
+func relativeChange(baseline, current float64) float64 {
+	switch {
+	case baseline == current:
+		return 0
+	case baseline == 0 && current > 0:
+		return math.Inf(1)
+	case baseline == 0:
+		return -100
+	}
+	return (current - baseline) / baseline * 100
+}
+
+// formatChange formats the change from the baseline to the current value, in percent.
+func formatChange(baseline, current float64) string {
+	if baseline == 0 && current != 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.2f%%", relativeChange(baseline, current))
+}
+
+// formatLatency formats a latency in milliseconds.
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
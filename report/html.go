@@ -0,0 +1,117 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"strconv"
+	"time"
+)
+
+//go:embed report.html
+var htmlTemplate string
+
+// htmlReport is the data of the HTML report template.
+type htmlReport struct {
+	*Report
+	LatencyChart   template.HTML
+	RPSChart       template.HTML
+	HistogramChart template.HTML
+	LagChart       template.HTML
+}
+
+// SaveHTML writes the report to a self-contained HTML file, with its charts
+// drawn as inline SVG so that it can be viewed offline and shared as is.
+func (r *Report) SaveHTML(path string) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"latency": formatLatency,
+		"percent": func(value float64) string { return fmt.Sprintf("%.2f%%", value) },
+		"share": func(count, total int) float64 {
+			if total == 0 {
+				return 0
+			}
+			return float64(count) / float64(total) * 100
+		},
+		"number": func(value float64) string { return strconv.FormatFloat(value, 'f', 2, 64) },
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	defer file.Close()
+
+	if err := tmpl.Execute(file, r.htmlReport()); err != nil {
+		return fmt.Errorf("failed to write HTML report: %w", err)
+	}
+	return nil
+}
+
+// htmlReport draws the charts of the report.
+func (r *Report) htmlReport() htmlReport {
+	offsets := make([]float64, len(r.Timeline))
+	rps := make([]float64, len(r.Timeline))
+	p50 := make([]float64, len(r.Timeline))
+	p99 := make([]float64, len(r.Timeline))
+	average := make([]float64, len(r.Timeline))
+	for i, interval := range r.Timeline {
+		offsets[i] = interval.Offset.Seconds()
+		rps[i] = interval.RPS
+		p50[i] = milliseconds(interval.P50Latency)
+		p99[i] = milliseconds(interval.P99Latency)
+		average[i] = milliseconds(interval.AverageLatency)
+	}
+
+	labels := make([]string, len(r.Histogram))
+	counts := make([]float64, len(r.Histogram))
+	for i, bucket := range r.Histogram {
+		switch {
+		case bucket.UpperBound > 0:
+			labels[i] = "≤ " + bucket.UpperBound.String()
+		case i > 0:
+			labels[i] = "> " + r.Histogram[i-1].UpperBound.String()
+		}
+		counts[i] = float64(bucket.Count)
+	}
+
+	formatSeconds := func(s float64) string { return strconv.FormatFloat(s, 'f', 1, 64) + "s" }
+	formatMs := func(ms float64) string { return strconv.FormatFloat(ms, 'f', -1, 64) + "ms" }
+	formatCount := func(n float64) string { return strconv.FormatFloat(n, 'f', -1, 64) }
+
+	var lagChart template.HTML
+	if r.Freshness != nil && len(r.Freshness.Distribution) > 0 {
+		lagLabels := make([]string, len(r.Freshness.Distribution))
+		lagCounts := make([]float64, len(r.Freshness.Distribution))
+		for i, bucket := range r.Freshness.Distribution {
+			lagLabels[i] = strconv.FormatUint(bucket.Lag, 10)
+			if bucket.OrMore {
+				lagLabels[i] += "+"
+			}
+			lagCounts[i] = float64(bucket.Count)
+		}
+		lagChart = barChart(lagLabels, lagCounts, "#74c0fc", formatCount)
+	}
+
+	return htmlReport{
+		Report: r,
+		LatencyChart: lineChart(offsets, []series{
+			{name: "P50", color: "#2b8a3e", values: p50},
+			{name: "Average", color: "#1c7ed6", values: average},
+			{name: "P99", color: "#e8590c", values: p99},
+		}, formatSeconds, formatMs),
+		RPSChart: lineChart(offsets, []series{
+			{name: "RPS", color: "#1c7ed6", values: rps},
+		}, formatSeconds, formatCount),
+		HistogramChart: barChart(labels, counts, "#1c7ed6", formatCount),
+		LagChart:       lagChart,
+	}
+}
+
+// milliseconds returns the duration in milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,322 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/chaos"
+	"github.com/commoddity/relay-util/v2/record"
+	"github.com/commoddity/relay-util/v2/redact"
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+// formatVersion is the version of the report file format.
+const formatVersion = 1
+
+// timelineIntervals are the interval durations the timeline may use.
+// The shortest one that keeps the timeline within maxTimelineIntervals is used.
+var timelineIntervals = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	1 * time.Minute,
+}
+
+const (
+	// maxTimelineIntervals is the number of intervals the timeline aims to stay within.
+	maxTimelineIntervals = 120
+	// maxSuccessBodies is the number of distinct success bodies kept in the report.
+	maxSuccessBodies = 20
+	// maxSuccessBodyLength is the length at which success bodies are truncated in the report.
+	maxSuccessBodyLength = 1000
+)
+
+// histogramBounds are the upper bounds of the latency histogram buckets.
+// Latencies above the last bound fall in a final, unbounded bucket.
+var histogramBounds = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+type (
+	// Report is the persisted result of a run, used to compare runs with each other.
+	// Durations are encoded in JSON as nanoseconds.
+	Report struct {
+		Version   int         `json:"version"`
+		CreatedAt time.Time   `json:"created_at"`
+		Config    Config      `json:"config"`
+		Stats     relay.Stats `json:"stats"`
+		Histogram []Bucket    `json:"histogram"`
+		Errors    []Error     `json:"errors"`
+		// Timeline groups the relays by the interval of the run in which they were sent.
+		Timeline      []Interval `json:"timeline"`
+		SuccessBodies []Body     `json:"success_bodies"`
+		// Faults summarizes the relays by the fault the chaos proxy injected into them, if it was used.
+		Faults []chaos.Summary `json:"faults,omitempty"`
+		// Credentials breaks the relays down by the credential they were sent with, if credentials were rotated.
+		Credentials []relay.CredentialStats `json:"credentials,omitempty"`
+		// Headers breaks the relays down by the value of each captured response header, if headers were captured.
+		Headers []relay.HeaderValueStats `json:"headers,omitempty"`
+		// Freshness is how far behind the chain head the responses were, if it was tracked.
+		Freshness *relay.Freshness `json:"freshness,omitempty"`
+		// Captures are the slowest and a sample of the failed relays with their full request and response, if they were captured.
+		Captures *relay.Captures `json:"captures,omitempty"`
+		// Replay compares the responses of replayed relays with the recorded ones, if the run was a replay.
+		Replay *record.Comparison `json:"replay,omitempty"`
+	}
+
+	// Config is the configuration of the run, with its secrets masked.
+	Config struct {
+		URL        string        `json:"url"`
+		Method     string        `json:"method"`
+		Body       string        `json:"body,omitempty"`
+		Headers    http.Header   `json:"headers,omitempty"`
+		Executions int           `json:"executions"`
+		Goroutines int           `json:"goroutines"`
+		Wait       time.Duration `json:"wait"`
+		Timeout    time.Duration `json:"timeout"`
+	}
+
+	// Bucket counts the successful relays with a latency up to its upper bound
+	// and above the upper bound of the previous bucket. The last bucket has no upper bound.
+	Bucket struct {
+		UpperBound time.Duration `json:"upper_bound,omitempty"`
+		Count      int           `json:"count"`
+	}
+
+	// Error counts the failed relays by error reason.
+	Error struct {
+		Reason string `json:"reason"`
+		Count  int    `json:"count"`
+	}
+
+	// Interval summarizes the relays sent during an interval of the run,
+	// starting at Offset from the start of the run.
+	Interval struct {
+		Offset         time.Duration `json:"offset"`
+		Duration       time.Duration `json:"duration"`
+		Relays         int           `json:"relays"`
+		FailedRelays   int           `json:"failed_relays"`
+		RPS            float64       `json:"rps"`
+		AverageLatency time.Duration `json:"average_latency"`
+		P50Latency     time.Duration `json:"p50_latency"`
+		P99Latency     time.Duration `json:"p99_latency"`
+	}
+
+	// Body counts the successful relays by response body.
+	Body struct {
+		Body  string `json:"body"`
+		Count int    `json:"count"`
+	}
+)
+
+// New builds the report of the relays sent by SendRelays.
+func New(u *relay.Util) *Report {
+	method, body := http.MethodGet, redact.Body(string(u.Body))
+	if u.Body != nil {
+		method = http.MethodPost
+	}
+	if source, ok := u.Source.(fmt.Stringer); ok {
+		method, body = http.MethodPost, source.String()
+	}
+
+	report := &Report{
+		Version:   formatVersion,
+		CreatedAt: time.Now().UTC(),
+		Config: Config{
+			URL:        redact.URL(u.URL),
+			Method:     method,
+			Body:       body,
+			Headers:    redact.Headers(u.Headers),
+			Executions: u.Executions,
+			Goroutines: u.Goroutines,
+			Wait:       u.Wait,
+			Timeout:    u.Timeout,
+		},
+		Stats: u.Stats(),
+	}
+
+	report.Histogram = make([]Bucket, len(histogramBounds)+1)
+	for i, bound := range histogramBounds {
+		report.Histogram[i].UpperBound = bound
+	}
+
+	errorReasons := make(map[string]int)
+	successBodies := make(map[string]int)
+	for _, result := range u.Results {
+		if result.Err {
+			errorReasons[result.ErrReason]++
+			continue
+		}
+		successBodies[result.SuccessBody]++
+		latency := time.Duration(result.Latency) * time.Millisecond
+		i := sort.Search(len(histogramBounds), func(i int) bool { return latency <= histogramBounds[i] })
+		report.Histogram[i].Count++
+	}
+
+	for reason, count := range errorReasons {
+		report.Errors = append(report.Errors, Error{Reason: reason, Count: count})
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		if report.Errors[i].Count != report.Errors[j].Count {
+			return report.Errors[i].Count > report.Errors[j].Count
+		}
+		return report.Errors[i].Reason < report.Errors[j].Reason
+	})
+
+	// Bodies that only differ by their secrets are merged once masked
+	redactedBodies := make(map[string]int, len(successBodies))
+	for body, count := range successBodies {
+		redactedBodies[redact.Body(body)] += count
+	}
+	for body, count := range redactedBodies {
+		if len(body) > maxSuccessBodyLength {
+			body = body[:maxSuccessBodyLength] + "…"
+		}
+		report.SuccessBodies = append(report.SuccessBodies, Body{Body: body, Count: count})
+	}
+	sort.Slice(report.SuccessBodies, func(i, j int) bool {
+		if report.SuccessBodies[i].Count != report.SuccessBodies[j].Count {
+			return report.SuccessBodies[i].Count > report.SuccessBodies[j].Count
+		}
+		return report.SuccessBodies[i].Body < report.SuccessBodies[j].Body
+	})
+	if len(report.SuccessBodies) > maxSuccessBodies {
+		report.SuccessBodies = report.SuccessBodies[:maxSuccessBodies]
+	}
+
+	report.Timeline = newTimeline(u.Results)
+	report.Faults = chaos.Summarize(u.Results)
+	report.Credentials = relay.CredentialBreakdown(u.Results, u.ExecTime)
+	report.Headers = relay.HeaderBreakdown(u.Results, u.CaptureHeaders, u.ExecTime)
+	report.Freshness = u.Freshness()
+	report.Captures = u.Captures()
+
+	return report
+}
+
+// newTimeline groups the relays by the interval of the run in which they were sent.
+func newTimeline(results []relay.RelayResult) []Interval {
+	if len(results) == 0 {
+		return nil
+	}
+
+	start, end := results[0].StartedAt, results[0].StartedAt
+	for _, result := range results {
+		if result.StartedAt.Before(start) {
+			start = result.StartedAt
+		}
+		if result.StartedAt.After(end) {
+			end = result.StartedAt
+		}
+	}
+
+	interval := timelineIntervals[len(timelineIntervals)-1]
+	for _, d := range timelineIntervals {
+		if end.Sub(start)/d < maxTimelineIntervals {
+			interval = d
+			break
+		}
+	}
+
+	count := int(end.Sub(start)/interval) + 1
+	latencies := make([][]time.Duration, count)
+	timeline := make([]Interval, count)
+	for i := range timeline {
+		timeline[i].Offset = time.Duration(i) * interval
+		timeline[i].Duration = interval
+	}
+
+	for _, result := range results {
+		i := int(result.StartedAt.Sub(start) / interval)
+		timeline[i].Relays++
+		if result.Err {
+			timeline[i].FailedRelays++
+			continue
+		}
+		latencies[i] = append(latencies[i], time.Duration(result.Latency)*time.Millisecond)
+	}
+
+	for i := range timeline {
+		timeline[i].RPS = float64(timeline[i].Relays) / interval.Seconds()
+		if len(latencies[i]) == 0 {
+			continue
+		}
+		sort.Slice(latencies[i], func(a, b int) bool { return latencies[i][a] < latencies[i][b] })
+		var total time.Duration
+		for _, latency := range latencies[i] {
+			total += latency
+		}
+		timeline[i].AverageLatency = total / time.Duration(len(latencies[i]))
+		timeline[i].P50Latency = relay.Percentile(latencies[i], 0.5)
+		timeline[i].P99Latency = relay.Percentile(latencies[i], 0.99)
+	}
+
+	return timeline
+}
+
+// Save writes the report to a JSON file.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// Load reads a report from a JSON file written by Save.
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	if report.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported report version %d in %s, must be %d", report.Version, path, formatVersion)
+	}
+
+	return &report, nil
+}
+
+// ScenarioPath returns the path of the report of a scenario of a suite,
+// with the scenario name inserted before the extension, eg. report.mainnet.json.
+// An empty path stays empty.
+func ScenarioPath(path, scenario string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, scenario)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Dimensions of the charts of the HTML report, in SVG user units.
+const (
+	chartWidth   = 760
+	chartHeight  = 260
+	chartLeft    = 70
+	chartRight   = 20
+	chartTop     = 30
+	chartBottom  = 40
+	chartYTicks  = 5
+	chartXTicks  = 6
+	chartBarGap  = 6
+	chartFontPts = 11
+)
+
+// series is a named line of a line chart.
+type series struct {
+	name   string
+	color  string
+	values []float64
+}
+
+// lineChart renders the series as an inline SVG line chart.
+// The x values are shared by every series and labeled with formatX,
+// while the y axis starts at zero and is labeled with formatY.
+func lineChart(xs []float64, lines []series, formatX, formatY func(float64) string) template.HTML {
+	var svg strings.Builder
+	openChart(&svg)
+
+	maxX := 0.0
+	for _, x := range xs {
+		maxX = math.Max(maxX, x)
+	}
+	maxY := 0.0
+	for _, line := range lines {
+		for _, y := range line.values {
+			maxY = math.Max(maxY, y)
+		}
+	}
+	maxY = niceCeil(maxY)
+
+	plotWidth := float64(chartWidth - chartLeft - chartRight)
+	plotHeight := float64(chartHeight - chartTop - chartBottom)
+	xPos := func(x float64) float64 {
+		if maxX == 0 {
+			return chartLeft + plotWidth/2
+		}
+		return chartLeft + x/maxX*plotWidth
+	}
+	yPos := func(y float64) float64 { return chartTop + plotHeight - y/maxY*plotHeight }
+
+	yAxis(&svg, maxY, formatY)
+	for i := 0; i <= chartXTicks; i++ {
+		x := maxX * float64(i) / chartXTicks
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, xPos(x), chartHeight-chartBottom+18, template.HTMLEscapeString(formatX(x)))
+	}
+
+	for i, line := range lines {
+		points := make([]string, 0, len(line.values))
+		for j, y := range line.values {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", xPos(xs[j]), yPos(y)))
+		}
+		fmt.Fprintf(&svg, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, line.color, strings.Join(points, " "))
+		if len(points) == 1 {
+			fmt.Fprintf(&svg, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"/>`, xPos(xs[0]), yPos(line.values[0]), line.color)
+		}
+
+		// Legend, laid out from the top right corner
+		legendX := chartWidth - chartRight - 110*(len(lines)-i)
+		fmt.Fprintf(&svg, `<rect x="%d" y="8" width="12" height="12" fill="%s"/>`, legendX, line.color)
+		fmt.Fprintf(&svg, `<text x="%d" y="18">%s</text>`, legendX+16, template.HTMLEscapeString(line.name))
+	}
+
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// barChart renders the values as an inline SVG bar chart with a label under each bar.
+func barChart(labels []string, values []float64, color string, formatY func(float64) string) template.HTML {
+	var svg strings.Builder
+	openChart(&svg)
+
+	maxY := 0.0
+	for _, y := range values {
+		maxY = math.Max(maxY, y)
+	}
+	maxY = niceCeil(maxY)
+
+	plotWidth := float64(chartWidth - chartLeft - chartRight)
+	plotHeight := float64(chartHeight - chartTop - chartBottom)
+	barWidth := plotWidth/float64(max(len(values), 1)) - chartBarGap
+
+	yAxis(&svg, maxY, formatY)
+	for i, y := range values {
+		x := chartLeft + float64(i)*(barWidth+chartBarGap) + chartBarGap/2
+		height := y / maxY * plotHeight
+		fmt.Fprintf(&svg, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`,
+			x, chartTop+plotHeight-height, barWidth, height, color, template.HTMLEscapeString(labels[i]), template.HTMLEscapeString(formatY(y)))
+		fmt.Fprintf(&svg, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`, x+barWidth/2, chartHeight-chartBottom+18, template.HTMLEscapeString(labels[i]))
+	}
+
+	svg.WriteString(`</svg>`)
+	return template.HTML(svg.String())
+}
+
+// openChart writes the opening tag of a chart.
+func openChart(svg *strings.Builder) {
+	fmt.Fprintf(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" font-family="sans-serif" font-size="%d" fill="#444">`,
+		chartWidth, chartHeight, chartFontPts)
+}
+
+// yAxis writes the horizontal grid lines of a chart and their labels, from zero to maxY.
+func yAxis(svg *strings.Builder, maxY float64, formatY func(float64) string) {
+	plotHeight := float64(chartHeight - chartTop - chartBottom)
+	for i := 0; i <= chartYTicks; i++ {
+		y := chartTop + plotHeight - plotHeight*float64(i)/chartYTicks
+		fmt.Fprintf(svg, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`, chartLeft, y, chartWidth-chartRight, y)
+		fmt.Fprintf(svg, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`, chartLeft-8, y+4, template.HTMLEscapeString(formatY(maxY*float64(i)/chartYTicks)))
+	}
+}
+
+// niceCeil rounds the maximum of an axis up to 1, 2 or 5 times a power of ten, so that its ticks are round numbers.
+func niceCeil(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if value <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
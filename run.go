@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/commoddity/relay-util/v2/chaos"
+	"github.com/commoddity/relay-util/v2/config"
+	"github.com/commoddity/relay-util/v2/findmax"
+	"github.com/commoddity/relay-util/v2/log"
+	"github.com/commoddity/relay-util/v2/record"
+	"github.com/commoddity/relay-util/v2/redact"
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/report"
+	"github.com/commoddity/relay-util/v2/slo"
+	"github.com/commoddity/relay-util/v2/suite"
+	"github.com/commoddity/relay-util/v2/tracing"
+	"github.com/spf13/pflag"
+)
+
+// exitCodeThresholdsFailed is the exit code used when a run fails its SLO thresholds,
+// distinct from the exit code 1 used for invalid usage.
+const exitCodeThresholdsFailed = 2
+
+// runCommand sends relays to a specified service and logs the results.
+func runCommand(args []string) {
+	/* Flag Parsing */
+	var flags runFlags
+	flagSet := pflag.NewFlagSet("run", pflag.ExitOnError)
+	flags.register(flagSet)
+	flagSet.Usage = func() { runUsage(flagSet) }
+	_ = flagSet.Parse(args) // Exits on error
+
+	// Check if help was requested
+	if flags.help {
+		flagSet.Usage()
+		return // Exit gracefully without calling os.Exit
+	}
+
+	/* Redaction */
+	if err := redact.Configure(flags.redact); err != nil {
+		exitWithUsageError(err)
+	}
+
+	/* Config File */
+	if flags.configFile != "" {
+		file, err := config.Load(flags.configFile)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+
+		// Run every selected scenario as a suite if there is more than one
+		scenarios, err := file.Select(flags.scenarioNames)
+		if err != nil {
+			exitWithUsageError(err)
+		}
+		if len(scenarios) > 1 {
+			runSuite(scenarios, flags, args)
+			return
+		}
+
+		if err := flags.applyScenario(&scenarios[0], flagSet); err != nil {
+			exitWithUsageError(err)
+		}
+	}
+
+	runSingle(flags)
+}
+
+// runUsage prints the help text of the run command.
+func runUsage(flagSet *pflag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage: %s run [flags]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Sends relays to a specified service and logs the results. It supports various flags to control its behavior.\n")
+	fmt.Fprintf(os.Stderr, "The run command is the default, so its flags may also be used without it.\n\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	flagSet.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExample command:\n")
+	fmt.Fprintf(os.Stderr, "  %s run \\\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "    -u=https://path.rpc.grove.city/v1 \\\n")
+	fmt.Fprintf(os.Stderr, "    -H=\"target-service-id: F00C\" \\\n")
+	fmt.Fprintf(os.Stderr, "    -d='{\"jsonrpc\": \"2.0\", \"id\": 1, \"method\": \"eth_blockNumber\", \"params\": []}' \\\n")
+	fmt.Fprintf(os.Stderr, "    -x=3000 \\\n")
+	fmt.Fprintf(os.Stderr, "    -w=10 \\\n")
+	fmt.Fprintf(os.Stderr, "    -g=500 \\\n")
+	fmt.Fprintf(os.Stderr, "    -t=20 \\\n")
+	fmt.Fprintf(os.Stderr, "    -H=\"Authorization: api_key_123\" \\\n")
+	fmt.Fprintf(os.Stderr, "    -H=\"Custom-Header: value\"\n")
+}
+
+// runSingle sends the relays of a single scenario and logs the results.
+func runSingle(flags runFlags) {
+	if err := flags.validateFindMax(); err != nil {
+		exitWithUsageError(err)
+	}
+
+	/* SLO Thresholds */
+	thresholds, err := flags.slo.Thresholds()
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid SLO threshold: %w", err))
+	}
+
+	/* Tracing Init */
+	tracingConfig := flags.tracingConfig()
+	tracer, err := tracing.NewProvider(context.Background(), tracingConfig)
+	if err != nil {
+		fmt.Printf("🚫 Failed to initialize tracing: %v\n", err)
+		os.Exit(1)
+	}
+
+	/* Relay Util Init */
+	relayConfig, err := flags.relayConfig()
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	relayConfig.Tracer = tracer.Tracer
+
+	/* Recording */
+	var recorder *record.Recorder
+	if flags.recordPath != "" {
+		recorder = record.New(flags.recordConfig())
+		relayConfig.Recorder = recorder
+	}
+
+	/* Chaos Proxy Init */
+	proxy, err := startChaos(flags, &relayConfig)
+	if err != nil {
+		exitWithUsageError(err)
+	}
+	if proxy != nil {
+		defer proxy.Close()
+	}
+
+	relayUtil, err := relay.NewRelayUtil(relayConfig)
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	/* Send Relays */
+
+	log.PrintConfig(relayUtil)
+	log.PrintTracingConfig(tracingConfig)
+	if proxy != nil {
+		log.PrintChaosConfig(proxy.Config())
+	}
+
+	if flags.findMax {
+		result, err := findmax.Run(findmax.Config{
+			Relay:      relayConfig,
+			Start:      relayConfig.Goroutines,
+			Step:       flags.findMaxStep,
+			Limit:      flags.findMaxLimit,
+			Thresholds: thresholds,
+			OnStep:     log.LogFindMaxStep,
+		})
+		if err != nil {
+			exitWithUsageError(fmt.Errorf("failed to find the max throughput: %w", err))
+		}
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			fmt.Printf("🚫 Failed to flush traces: %v\n", err)
+		}
+		log.LogFindMax(result)
+		return
+	}
+
+	relayUtil.SendRelays()
+	if proxy != nil {
+		proxy.Attribute(relayUtil.CollectResults())
+	}
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		fmt.Printf("🚫 Failed to flush traces: %v\n", err)
+	}
+
+	log.LogResults(relayUtil)
+	saveReports(relayUtil, flags.savePath, flags.htmlPath)
+	saveRecording(recorder, flags.recordPath)
+
+	if len(thresholds) > 0 {
+		results := slo.Check(thresholds, relayUtil.Stats())
+		log.LogThresholds(results)
+		if !slo.Passed(results) {
+			os.Exit(exitCodeThresholdsFailed)
+		}
+	}
+}
+
+// runSuite runs several scenarios of a config file as a suite and logs a combined summary.
+// Each scenario is resolved against its own copy of the command line flags,
+// so that flags set on the command line override the values of every scenario.
+func runSuite(scenarios []config.Scenario, flags runFlags, args []string) {
+	if flags.findMax {
+		exitWithUsageError(fmt.Errorf("--find-max cannot be used with more than one scenario"))
+	}
+
+	/* Tracing Init */
+	tracingConfig := flags.tracingConfig()
+	tracer, err := tracing.NewProvider(context.Background(), tracingConfig)
+	if err != nil {
+		fmt.Printf("🚫 Failed to initialize tracing: %v\n", err)
+		os.Exit(1)
+	}
+
+	/* Scenario Init */
+	suiteScenarios := make([]suite.Scenario, 0, len(scenarios))
+	proxies := make(map[string]*chaos.Proxy)
+	recorders := make(map[string]*record.Recorder)
+	for i := range scenarios {
+		var scenarioFlags runFlags
+		flagSet := pflag.NewFlagSet(scenarios[i].Name, pflag.ContinueOnError)
+		scenarioFlags.register(flagSet)
+		if err := flagSet.Parse(args); err != nil {
+			exitWithUsageError(err)
+		}
+		if err := scenarioFlags.applyScenario(&scenarios[i], flagSet); err != nil {
+			exitWithUsageError(err)
+		}
+
+		relayConfig, err := scenarioFlags.relayConfig()
+		if err != nil {
+			exitWithUsageError(fmt.Errorf("scenario %q: %w", scenarios[i].Name, err))
+		}
+		relayConfig.Tracer = tracer.Tracer
+		relayConfig.Quiet = flags.parallel
+		if flags.recordPath != "" {
+			recorders[scenarios[i].Name] = record.New(flags.recordConfig())
+			relayConfig.Recorder = recorders[scenarios[i].Name]
+		}
+
+		proxy, err := startChaos(scenarioFlags, &relayConfig)
+		if err != nil {
+			exitWithUsageError(fmt.Errorf("scenario %q: %w", scenarios[i].Name, err))
+		}
+		if proxy != nil {
+			defer proxy.Close()
+			proxies[scenarios[i].Name] = proxy
+		}
+
+		thresholds, err := scenarioFlags.slo.Thresholds()
+		if err != nil {
+			exitWithUsageError(fmt.Errorf("scenario %q: invalid SLO threshold: %w", scenarios[i].Name, err))
+		}
+
+		suiteScenarios = append(suiteScenarios, suite.Scenario{
+			Name:       scenarios[i].Name,
+			Config:     relayConfig,
+			Thresholds: thresholds,
+		})
+	}
+
+	/* Send Relays */
+	log.PrintTracingConfig(tracingConfig)
+
+	results, err := suite.Run(suite.Config{
+		Scenarios: suiteScenarios,
+		Parallel:  flags.parallel,
+		BeforeScenario: func(scenario suite.Scenario, u *relay.Util) {
+			log.PrintScenarioHeader(scenario.Name)
+			log.PrintConfig(u)
+			if proxy, ok := proxies[scenario.Name]; ok {
+				log.PrintChaosConfig(proxy.Config())
+			}
+		},
+		AfterScenario: func(result suite.Result) {
+			if proxy, ok := proxies[result.Name]; ok {
+				proxy.Attribute(result.Util.CollectResults())
+			}
+			if flags.parallel {
+				log.PrintScenarioHeader(result.Name)
+			}
+			log.LogResults(result.Util)
+			saveReports(result.Util, report.ScenarioPath(flags.savePath, result.Name), report.ScenarioPath(flags.htmlPath, result.Name))
+			saveRecording(recorders[result.Name], report.ScenarioPath(flags.recordPath, result.Name))
+			if len(result.Thresholds) > 0 {
+				log.LogThresholds(result.Thresholds)
+			}
+		},
+	})
+	if err != nil {
+		exitWithUsageError(fmt.Errorf("invalid configuration: %w", err))
+	}
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		fmt.Printf("🚫 Failed to flush traces: %v\n", err)
+	}
+
+	log.LogSuiteSummary(results)
+	if !suite.Passed(results) {
+		os.Exit(exitCodeThresholdsFailed)
+	}
+}
+
+// startChaos starts a chaos proxy to the URL of the relay config if any chaos flag is set,
+// and sends the relays through it. It returns nil if no chaos flag is set.
+func startChaos(flags runFlags, relayConfig *relay.Config) (*chaos.Proxy, error) {
+	chaosConfig, err := flags.chaosConfig()
+	if err != nil || !chaosConfig.Enabled() {
+		return nil, err
+	}
+
+	client, err := relay.NewHTTPClient(relayConfig.Transport, relayConfig.Timeout, relayConfig.Goroutines)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	chaosConfig.Target = relayConfig.URL
+	chaosConfig.Client = client
+
+	proxy, err := chaos.Start(chaosConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chaos configuration: %w", err)
+	}
+	relayConfig.Via = proxy.URL()
+	return proxy, nil
+}
+
+// saveReports saves the report of a run as JSON and as HTML to the paths that are given.
+// A report that fails to save does not fail the run.
+func saveReports(u *relay.Util, jsonPath, htmlPath string) {
+	if jsonPath == "" && htmlPath == "" {
+		return
+	}
+
+	writeReports(report.New(u), jsonPath, htmlPath)
+}
+
+// writeReports writes a report as JSON and as HTML to the paths that are given.
+func writeReports(runReport *report.Report, jsonPath, htmlPath string) {
+	if jsonPath != "" {
+		if err := runReport.Save(jsonPath); err != nil {
+			fmt.Printf("🚫 Failed to save report: %v\n", err)
+		} else {
+			log.LogReportSaved(jsonPath)
+		}
+	}
+	if htmlPath != "" {
+		if err := runReport.SaveHTML(htmlPath); err != nil {
+			fmt.Printf("🚫 Failed to save HTML report: %v\n", err)
+		} else {
+			log.LogReportSaved(htmlPath)
+		}
+	}
+}
+
+// saveRecording saves the recorded relays of a run to the path, if relays were recorded.
+func saveRecording(recorder *record.Recorder, path string) {
+	if recorder == nil || path == "" {
+		return
+	}
+
+	if err := recorder.Save(path); err != nil {
+		fmt.Printf("🚫 Failed to save recording: %v\n", err)
+		return
+	}
+	log.LogRecordingSaved(path, recorder.Len())
+}
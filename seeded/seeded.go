@@ -0,0 +1,31 @@
+// Package seeded derives the random choices of relays from a seed and the relay ID,
+// so that the corpus and the request generators send the same requests for the same seed.
+package seeded
+
+// golden is the increment of the splitmix64 sequence.
+const golden = 0x9e3779b97f4a7c15
+
+// Rand is a splitmix64 random number generator, cheap enough to seed for every relay.
+type Rand struct {
+	state uint64
+}
+
+// Hash hashes x into a well-distributed pseudo-random number, as the splitmix64 step.
+func Hash(x uint64) uint64 {
+	x += golden
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// New creates a random number generator seeded with the seed and the ID of a relay.
+func New(seed uint64, id int32) *Rand {
+	return &Rand{state: seed ^ uint64(id)}
+}
+
+// Intn returns a random number in [0, n).
+func (r *Rand) Intn(n int) int {
+	x := Hash(r.state)
+	r.state += golden
+	return int(x % uint64(n))
+}
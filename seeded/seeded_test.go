@@ -0,0 +1,35 @@
+package seeded
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHashMatchesSplitMix64(t *testing.T) {
+	// The first outputs of the reference splitmix64 generator seeded with 0
+	if got := Hash(0); got != 0xe220a8397b1dcdaf {
+		t.Errorf("Hash(0) = %#x, want 0xe220a8397b1dcdaf", got)
+	}
+	if got := Hash(golden); got != 0x6e789e6aa1b965f4 {
+		t.Errorf("Hash(golden) = %#x, want 0x6e789e6aa1b965f4", got)
+	}
+}
+
+func TestIntnFollowsTheSequence(t *testing.T) {
+	r := New(0, 0)
+	want := []uint64{0xe220a8397b1dcdaf, 0x6e789e6aa1b965f4}
+	for i, w := range want {
+		if got := r.Intn(math.MaxInt64); got != int(w%math.MaxInt64) {
+			t.Errorf("Intn() #%d = %d, want %d", i+1, got, w%math.MaxInt64)
+		}
+	}
+}
+
+func TestNewIsDeterministic(t *testing.T) {
+	a, b := New(42, 7), New(42, 7)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Intn(1000), b.Intn(1000); x != y {
+			t.Fatalf("Intn() #%d = %d and %d, want the same number for the same seed and ID", i+1, x, y)
+		}
+	}
+}
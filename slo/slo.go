@@ -0,0 +1,185 @@
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/parse"
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+type (
+	// Threshold is a single pass/fail assertion on the statistics of a run.
+	Threshold struct {
+		Name  string
+		Limit string
+		check func(relay.Stats) (actual string, passed bool)
+	}
+
+	// Spec is the textual form of a set of thresholds, as given on the
+	// command line or in a config file. Empty fields are not checked.
+	Spec struct {
+		MaxFailureRate string   `yaml:"max-failure-rate" toml:"max-failure-rate"`
+		MaxP99         string   `yaml:"max-p99" toml:"max-p99"`
+		MinRPS         string   `yaml:"min-rps" toml:"min-rps"`
+		MaxErrorCodes  []string `yaml:"max-error-codes" toml:"max-error-codes"`
+	}
+
+	// Result is the outcome of checking a threshold against the statistics of a run.
+	Result struct {
+		Name   string
+		Limit  string
+		Actual string
+		Passed bool
+	}
+)
+
+// Thresholds parses the spec into the thresholds it describes.
+func (s Spec) Thresholds() ([]Threshold, error) {
+	var thresholds []Threshold
+
+	if s.MaxFailureRate != "" {
+		percent, err := ParsePercent(s.MaxFailureRate)
+		if err != nil {
+			return nil, fmt.Errorf("max failure rate: %w", err)
+		}
+		thresholds = append(thresholds, MaxFailureRate(percent))
+	}
+	if s.MaxP99 != "" {
+		latency, err := ParseLatency(s.MaxP99)
+		if err != nil {
+			return nil, fmt.Errorf("max p99: %w", err)
+		}
+		thresholds = append(thresholds, MaxP99(latency))
+	}
+	if s.MinRPS != "" {
+		rps, err := strconv.ParseFloat(strings.TrimSpace(s.MinRPS), 64)
+		if err != nil || rps < 0 {
+			return nil, fmt.Errorf("min RPS: invalid value %q, must be a number greater than or equal to 0", s.MinRPS)
+		}
+		thresholds = append(thresholds, MinRPS(rps))
+	}
+	for _, value := range s.MaxErrorCodes {
+		code, count, err := ParseErrorCode(value)
+		if err != nil {
+			return nil, fmt.Errorf("max error code: %w", err)
+		}
+		thresholds = append(thresholds, MaxErrorCode(code, count))
+	}
+
+	return thresholds, nil
+}
+
+// MaxFailureRate asserts that at most the given percentage of relays failed.
+func MaxFailureRate(percent float64) Threshold {
+	return Threshold{
+		Name:  "Failure rate",
+		Limit: fmt.Sprintf("<= %.2f%%", percent),
+		check: func(s relay.Stats) (string, bool) {
+			return fmt.Sprintf("%.2f%%", s.FailureRate), s.FailureRate <= percent
+		},
+	}
+}
+
+// noSuccessfulRelays is the actual value of the thresholds on successful relays when there are none.
+const noSuccessfulRelays = "no successful relays"
+
+// MaxP99 asserts that the p99 latency of successful relays is at most the given duration.
+// It fails if no relay succeeded, as failed relays have no latency.
+func MaxP99(limit time.Duration) Threshold {
+	return Threshold{
+		Name:  "P99 latency",
+		Limit: fmt.Sprintf("<= %s", limit),
+		check: func(s relay.Stats) (string, bool) {
+			if s.SuccessfulRelays == 0 {
+				return noSuccessfulRelays, false
+			}
+			return s.P99Latency.String(), s.P99Latency <= limit
+		},
+	}
+}
+
+// MinRPS asserts that relays succeeded at a rate of at least the given requests per second,
+// so that relays failing at once, such as on connection resets, do not raise the rate.
+func MinRPS(rps float64) Threshold {
+	return Threshold{
+		Name:  "RPS",
+		Limit: fmt.Sprintf(">= %.2f", rps),
+		check: func(s relay.Stats) (string, bool) {
+			if s.SuccessfulRelays == 0 {
+				return noSuccessfulRelays, rps == 0
+			}
+			successRPS := s.RPS * float64(s.SuccessfulRelays) / float64(s.TotalRelays)
+			return fmt.Sprintf("%.2f", successRPS), successRPS >= rps
+		},
+	}
+}
+
+// MaxErrorCode asserts that at most the given number of relays failed with a JSON-RPC error code.
+func MaxErrorCode(code, count int) Threshold {
+	return Threshold{
+		Name:  fmt.Sprintf("Error code %d", code),
+		Limit: fmt.Sprintf("<= %d", count),
+		check: func(s relay.Stats) (string, bool) {
+			return strconv.Itoa(s.ErrorCodes[code]), s.ErrorCodes[code] <= count
+		},
+	}
+}
+
+// Check checks each threshold against the statistics of a run.
+func Check(thresholds []Threshold, stats relay.Stats) []Result {
+	results := make([]Result, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		actual, passed := threshold.check(stats)
+		results = append(results, Result{
+			Name:   threshold.Name,
+			Limit:  threshold.Limit,
+			Actual: actual,
+			Passed: passed,
+		})
+	}
+	return results
+}
+
+// Passed returns true if every threshold passed.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// ParsePercent parses a percentage such as "1%" or "0.5".
+func ParsePercent(value string) (float64, error) {
+	return parse.Percent(value)
+}
+
+// ParseErrorCode parses a JSON-RPC error code threshold such as "-32000=0" into its code and max count.
+func ParseErrorCode(value string) (code, count int, err error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid error code threshold %q, must be <code>=<count> such as -32000=0", value)
+	}
+	code, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid JSON-RPC error code in %q", value)
+	}
+	count, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || count < 0 {
+		return 0, 0, fmt.Errorf("invalid max count in %q, must be greater than or equal to 0", value)
+	}
+	return code, count, nil
+}
+
+// ParseLatency parses a latency such as "500ms" or "1.5s". A plain number is taken as milliseconds.
+func ParseLatency(value string) (time.Duration, error) {
+	latency, err := parse.Duration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid latency %q, must be a duration such as 500ms", strings.TrimSpace(value))
+	}
+	return latency, nil
+}
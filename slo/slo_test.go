@@ -0,0 +1,164 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/commoddity/relay-util/v2/relay"
+)
+
+func TestSpecThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      Spec
+		wantNames []string
+		wantErr   bool
+	}{
+		{name: "empty", spec: Spec{}},
+		{
+			name:      "all thresholds",
+			spec:      Spec{MaxFailureRate: "1%", MaxP99: "500ms", MinRPS: "200", MaxErrorCodes: []string{"-32000=0", "-32005=10"}},
+			wantNames: []string{"Failure rate", "P99 latency", "RPS", "Error code -32000", "Error code -32005"},
+		},
+		{name: "p99 in plain milliseconds", spec: Spec{MaxP99: "750"}, wantNames: []string{"P99 latency"}},
+		{name: "invalid failure rate", spec: Spec{MaxFailureRate: "lots"}, wantErr: true},
+		{name: "invalid p99", spec: Spec{MaxP99: "fast"}, wantErr: true},
+		{name: "negative RPS", spec: Spec{MinRPS: "-1"}, wantErr: true},
+		{name: "invalid error code", spec: Spec{MaxErrorCodes: []string{"-32000"}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			thresholds, err := test.spec.Thresholds()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Thresholds() error = %v, want error %t", err, test.wantErr)
+			}
+			if len(thresholds) != len(test.wantNames) {
+				t.Fatalf("got %d thresholds, want %d", len(thresholds), len(test.wantNames))
+			}
+			for i, threshold := range thresholds {
+				if threshold.Name != test.wantNames[i] {
+					t.Errorf("threshold %d = %s, want %s", i, threshold.Name, test.wantNames[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	healthy := relay.Stats{
+		TotalRelays:      1000,
+		SuccessfulRelays: 995,
+		FailedRelays:     5,
+		FailureRate:      0.5,
+		RPS:              250,
+		P99Latency:       400 * time.Millisecond,
+		ErrorCodes:       map[int]int{-32000: 5},
+	}
+	// Every relay failed at once, so the run has no latencies and a high RPS
+	down := relay.Stats{
+		TotalRelays:  1000,
+		FailedRelays: 1000,
+		FailureRate:  100,
+		RPS:          5000,
+	}
+
+	tests := []struct {
+		name       string
+		threshold  Threshold
+		stats      relay.Stats
+		wantActual string
+		wantPassed bool
+	}{
+		{name: "failure rate within limit", threshold: MaxFailureRate(1), stats: healthy, wantActual: "0.50%", wantPassed: true},
+		{name: "failure rate above limit", threshold: MaxFailureRate(0.1), stats: healthy, wantActual: "0.50%"},
+		{name: "p99 within limit", threshold: MaxP99(500 * time.Millisecond), stats: healthy, wantActual: "400ms", wantPassed: true},
+		{name: "p99 above limit", threshold: MaxP99(300 * time.Millisecond), stats: healthy, wantActual: "400ms"},
+		{name: "p99 without successful relays", threshold: MaxP99(500 * time.Millisecond), stats: down, wantActual: "no successful relays"},
+		{name: "RPS of successful relays", threshold: MinRPS(200), stats: healthy, wantActual: "248.75", wantPassed: true},
+		{name: "RPS below limit", threshold: MinRPS(249), stats: healthy, wantActual: "248.75"},
+		{name: "RPS without successful relays", threshold: MinRPS(200), stats: down, wantActual: "no successful relays"},
+		{name: "error code within limit", threshold: MaxErrorCode(-32000, 5), stats: healthy, wantActual: "5", wantPassed: true},
+		{name: "error code above limit", threshold: MaxErrorCode(-32000, 0), stats: healthy, wantActual: "5"},
+		{name: "error code not seen", threshold: MaxErrorCode(-32005, 0), stats: healthy, wantActual: "0", wantPassed: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := Check([]Threshold{test.threshold}, test.stats)[0]
+			if result.Actual != test.wantActual || result.Passed != test.wantPassed {
+				t.Errorf("Check() = %s passed %t, want %s passed %t", result.Actual, result.Passed, test.wantActual, test.wantPassed)
+			}
+		})
+	}
+}
+
+func TestAllFailedRunFailsLatencyAndRPSThresholds(t *testing.T) {
+	thresholds, err := Spec{MaxP99: "500ms", MinRPS: "10"}.Thresholds()
+	if err != nil {
+		t.Fatalf("Thresholds() error = %v", err)
+	}
+	stats := relay.Stats{TotalRelays: 100, FailedRelays: 100, FailureRate: 100, RPS: 1000}
+
+	results := Check(thresholds, stats)
+	if Passed(results) {
+		t.Errorf("Passed() = true for a run where every relay failed, results %+v", results)
+	}
+	for _, result := range results {
+		if result.Passed {
+			t.Errorf("%s passed with %s, want it to fail", result.Name, result.Actual)
+		}
+	}
+}
+
+func TestParseErrorCode(t *testing.T) {
+	tests := []struct {
+		value     string
+		wantCode  int
+		wantCount int
+		wantErr   bool
+	}{
+		{value: "-32000=0", wantCode: -32000, wantCount: 0},
+		{value: " -32005 = 10 ", wantCode: -32005, wantCount: 10},
+		{value: "-32000", wantErr: true},
+		{value: "rate=1", wantErr: true},
+		{value: "-32000=many", wantErr: true},
+		{value: "-32000=-1", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			code, count, err := ParseErrorCode(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseErrorCode(%q) error = %v, want error %t", test.value, err, test.wantErr)
+			}
+			if code != test.wantCode || count != test.wantCount {
+				t.Errorf("ParseErrorCode(%q) = %d, %d, want %d, %d", test.value, code, count, test.wantCode, test.wantCount)
+			}
+		})
+	}
+}
+
+func TestParseLatency(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "500ms", want: 500 * time.Millisecond},
+		{value: "1.5s", want: 1500 * time.Millisecond},
+		{value: "250", want: 250 * time.Millisecond},
+		{value: " 2s ", want: 2 * time.Second},
+		{value: "fast", wantErr: true},
+		{value: "-5ms", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseLatency(test.value)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseLatency(%q) error = %v, want error %t", test.value, err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ParseLatency(%q) = %s, want %s", test.value, got, test.want)
+			}
+		})
+	}
+}
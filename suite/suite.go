@@ -0,0 +1,119 @@
+package suite
+
+import (
+	"sync"
+
+	"github.com/commoddity/relay-util/v2/relay"
+	"github.com/commoddity/relay-util/v2/slo"
+)
+
+type (
+	// Scenario is a named relay config with its SLO thresholds.
+	Scenario struct {
+		Name       string
+		Config     relay.Config
+		Thresholds []slo.Threshold
+	}
+
+	// Config configures how the scenarios of a suite are run.
+	Config struct {
+		Scenarios []Scenario
+		Parallel  bool
+		// BeforeScenario is called before each scenario starts sending relays.
+		BeforeScenario func(Scenario, *relay.Util)
+		// AfterScenario is called once each scenario has sent all its relays.
+		// In parallel mode, it is called in scenario order once all scenarios are done.
+		AfterScenario func(Result)
+	}
+
+	// Result holds the outcome of a single scenario of the suite.
+	Result struct {
+		Name       string
+		Util       *relay.Util
+		Stats      relay.Stats
+		Thresholds []slo.Result
+	}
+)
+
+// Passed returns true if the scenario passed all of its thresholds.
+func (r Result) Passed() bool {
+	return slo.Passed(r.Thresholds)
+}
+
+// Passed returns true if every scenario of the suite passed all of its thresholds.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run runs the scenarios of the suite, one after another or all at once,
+// and returns their results in scenario order.
+// The relay utils of all scenarios are created before any relay is sent,
+// so that an invalid scenario fails the suite before it starts.
+func Run(config Config) ([]Result, error) {
+	utils := make([]*relay.Util, len(config.Scenarios))
+	for i, scenario := range config.Scenarios {
+		util, err := relay.NewRelayUtil(scenario.Config)
+		if err != nil {
+			return nil, err
+		}
+		utils[i] = util
+	}
+
+	results := make([]Result, len(config.Scenarios))
+	run := func(i int) {
+		utils[i].SendRelays()
+		stats := utils[i].Stats()
+		results[i] = Result{
+			Name:       config.Scenarios[i].Name,
+			Util:       utils[i],
+			Stats:      stats,
+			Thresholds: slo.Check(config.Scenarios[i].Thresholds, stats),
+		}
+	}
+
+	if !config.Parallel {
+		for i, scenario := range config.Scenarios {
+			config.beforeScenario(scenario, utils[i])
+			run(i)
+			config.afterScenario(results[i])
+		}
+		return results, nil
+	}
+
+	for i, scenario := range config.Scenarios {
+		config.beforeScenario(scenario, utils[i])
+	}
+
+	var wg sync.WaitGroup
+	for i := range config.Scenarios {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		config.afterScenario(result)
+	}
+
+	return results, nil
+}
+
+func (c Config) beforeScenario(scenario Scenario, util *relay.Util) {
+	if c.BeforeScenario != nil {
+		c.BeforeScenario(scenario, util)
+	}
+}
+
+func (c Config) afterScenario(result Result) {
+	if c.AfterScenario != nil {
+		c.AfterScenario(result)
+	}
+}
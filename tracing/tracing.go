@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const serviceName = "relay-util"
+
+type (
+	Config struct {
+		OTLPEndpoint string
+		FilePath     string
+	}
+
+	// Provider wraps the tracer used for relays and the
+	// exporter resources that must be flushed on shutdown.
+	Provider struct {
+		Tracer   trace.Tracer
+		provider *sdktrace.TracerProvider
+		file     *os.File
+	}
+)
+
+// Enabled returns true if any span exporter is configured.
+func (c Config) Enabled() bool {
+	return c.OTLPEndpoint != "" || c.FilePath != ""
+}
+
+// NewProvider creates a new tracing provider from the config.
+// If no exporter is configured, the returned provider uses a no-op tracer.
+func NewProvider(ctx context.Context, config Config) (*Provider, error) {
+	if !config.Enabled() {
+		return &Provider{Tracer: noop.NewTracerProvider().Tracer(serviceName)}, nil
+	}
+
+	p := &Provider{}
+	var opts []sdktrace.TracerProviderOption
+
+	if config.OTLPEndpoint != "" {
+		exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.OTLPEndpoint))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	if config.FilePath != "" {
+		file, err := os.Create(config.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create trace file: %w", err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		p.file = file
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	opts = append(opts, sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))))
+
+	p.provider = sdktrace.NewTracerProvider(opts...)
+	p.Tracer = p.provider.Tracer(serviceName)
+
+	return p, nil
+}
+
+// Shutdown flushes all pending spans and closes the exporters.
+// The trace file is closed even if flushing fails, so that the spans written so far are kept.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	providerErr := p.provider.Shutdown(ctx)
+	var fileErr error
+	if p.file != nil {
+		fileErr = p.file.Close()
+	}
+	return errors.Join(providerErr, fileErr)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/pflag"
+)
+
+// version is the version of relay-util, set at build time with
+// -ldflags "-X main.version=<version>". If it is not set, the
+// module version recorded by go install is used instead.
+var version = ""
+
+// versionCommand prints the version of relay-util.
+func versionCommand(args []string) {
+	flagSet := pflag.NewFlagSet("version", pflag.ExitOnError)
+	help := flagSet.BoolP("help", "h", false, "Display help information")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s version\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Prints the version of relay-util and the Go version it was built with.\n")
+	}
+	_ = flagSet.Parse(args) // Exits on error
+
+	if *help {
+		flagSet.Usage()
+		return
+	}
+
+	fmt.Printf("relay-util %s (%s, %s/%s)\n", currentVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// currentVersion returns the version set at build time, or the module version if it was not set.
+func currentVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}